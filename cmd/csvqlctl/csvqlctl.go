@@ -23,6 +23,42 @@ const (
 	linesShortParam         = "l"
 	tableNameParam          = "collection"
 	tableNameShortParam     = "c"
+	groupByParam            = "group-by"
+	prettyParam             = "pretty"
+	splitRowsParam          = "split-rows"
+	splitBytesParam         = "split-bytes"
+	partitionByParam        = "partition-by"
+	maxOpenFilesParam       = "max-open-files"
+	templateParam           = "template"
+	overwriteParam          = "overwrite"
+	appendParam             = "append"
+	noClobberParam          = "no-clobber"
+	countParam              = "count"
+	timeoutParam            = "timeout"
+	maxRowsParam            = "max-rows"
+	noPagerParam            = "no-pager"
+	outputParam             = "output"
+	outputShortParam        = "o"
+	quietParam              = "quiet"
+	queryFileParam          = "query-file"
+	continueOnErrorParam    = "continue-on-error"
+	bindParam               = "param"
+	refreshParam            = "refresh"
+	ifExistsParam           = "if-exists"
+	primaryKeyParam         = "primary-key"
+	importModeParam         = "mode"
+	indexParam              = "index"
+	autoIndexParam          = "auto-index"
+	lazyParam               = "lazy"
+	lazyStatsParam          = "lazy-stats"
+	selectParam             = "select"
+	whereParam              = "where"
+	sampleParam             = "sample"
+	sampleByParam           = "sample-by"
+	seedParam               = "seed"
+	memoryLimitParam        = "memory-limit"
+	memoryLimitDefault      = 1000 * 1000 * 1000
+	engineParam             = "engine"
 )
 
 type CsvQlCtl interface {
@@ -57,27 +93,163 @@ func (c *csvQlCtl) Command() (*cobra.Command, error) {
 
 	command.
 		PersistentFlags().
-		StringVarP(&c.params.Query, queryParam, queryShortParam, "", "query param")
+		StringVarP(&c.params.Query, queryParam, queryShortParam, "", "query param, multiple statements are separated by `;`")
 
 	command.
 		PersistentFlags().
-		StringVarP(&c.params.Export, exportParam, exportShortParam, "", "export path")
+		StringVar(&c.params.QueryFile, queryFileParam, "", "file with statements separated by `;` to execute")
 
 	command.
 		PersistentFlags().
-		StringVarP(&c.params.Type, typeParam, typeShortParam, "", "format type [`jsonl`,`csv`]")
+		BoolVar(&c.params.ContinueOnError, continueOnErrorParam, false, "continue executing statements after a failure")
+
+	command.
+		PersistentFlags().
+		StringArrayVar(&c.params.Variables, bindParam, []string{}, "parameter bound to `:name` placeholders, in the `name=value` format")
+
+	command.
+		PersistentFlags().
+		StringVarP(&c.params.Export, exportParam, exportShortParam, "", "export path, `-` for stdout, `.gz`/`.zst` extensions are compressed")
+
+	command.
+		PersistentFlags().
+		StringVarP(&c.params.Type, typeParam, typeShortParam, "", "format type [`jsonl`,`csv`,`tsv`,`json`,`markdown`,`template`]")
 
 	command.
 		PersistentFlags().
 		StringVarP(&c.params.DataSourceName, storageParam, storageShortParam, "", "sqlite file")
 
+	command.
+		PersistentFlags().
+		StringVar(&c.params.Engine, engineParam, "sqlite", "storage engine [`sqlite`,`duckdb`], `duckdb` requires building with `-tags duckdb`")
+
 	command.
 		PersistentFlags().
 		IntVarP(&c.params.Lines, linesParam, linesShortParam, 0, "number of lines to be read")
 
-	if err := command.MarkPersistentFlagRequired(fileParam); err != nil {
-		return nil, fmt.Errorf("failed to validate flag %s: %w", fileParam, err)
-	}
+	command.
+		PersistentFlags().
+		BoolVar(&c.params.Refresh, refreshParam, false, "import files again even when unchanged since the last import into the storage")
+
+	command.
+		PersistentFlags().
+		StringVar(&c.params.IfExists, ifExistsParam, "replace", "import into existing tables [`replace`,`append`,`skip`,`fail`]")
+
+	command.
+		PersistentFlags().
+		StringSliceVar(&c.params.PrimaryKey, primaryKeyParam, []string{}, "columns of the unique index created on imported tables")
+
+	command.
+		PersistentFlags().
+		StringVar(&c.params.ImportMode, importModeParam, "insert", "import mode [`insert`,`upsert`], `upsert` updates rows with the same primary key")
+
+	command.
+		PersistentFlags().
+		StringArrayVar(&c.params.Indexes, indexParam, []string{}, "index created after the import, in the `table(col1,col2)` format")
+
+	command.
+		PersistentFlags().
+		BoolVar(&c.params.AutoIndex, autoIndexParam, false, "index imported columns that look like identifiers or keys (`id`, `*_id`, `*_key`)")
+
+	command.
+		PersistentFlags().
+		StringSliceVar(&c.params.Select, selectParam, []string{}, "columns imported from files, all columns when empty")
+
+	command.
+		PersistentFlags().
+		StringVar(&c.params.Where, whereParam, "", "sql expression filtering the rows imported from files, columns are compared as text")
+
+	command.
+		PersistentFlags().
+		StringVar(&c.params.Sample, sampleParam, "", "import a random sample of `N` rows or `pct%` of the rows of each file")
+
+	command.
+		PersistentFlags().
+		StringVar(&c.params.SampleBy, sampleByParam, "", "column whose values are each sampled with N rows")
+
+	command.
+		PersistentFlags().
+		Int64Var(&c.params.Seed, seedParam, 0, "seed of the sample to make it reproducible, `0` picks a random seed")
+
+	command.
+		PersistentFlags().
+		BoolVar(&c.params.Lazy, lazyParam, false, "query files through virtual tables reading them on each query instead of importing them")
+
+	command.
+		PersistentFlags().
+		BoolVar(&c.params.LazyStats, lazyStatsParam, false, "keep per block min/max values of lazy files in a .csvql-stats file to skip blocks, implies --lazy")
+
+	c.params.MemoryLimit = memoryLimitDefault
+	command.
+		PersistentFlags().
+		Var(&c.params.MemoryLimit, memoryLimitParam, "input size above which an in-memory storage spills to a temporary file (e.g. `4GB`), `0` disables it")
+
+	command.
+		PersistentFlags().
+		StringSliceVar(&c.params.GroupBy, groupByParam, []string{}, "columns used to nest rows in `json` export")
+
+	command.
+		PersistentFlags().
+		BoolVar(&c.params.Pretty, prettyParam, false, "indent `json` export")
+
+	command.
+		PersistentFlags().
+		IntVar(&c.params.SplitRows, splitRowsParam, 0, "maximum number of rows per exported file")
+
+	command.
+		PersistentFlags().
+		Var(&c.params.SplitBytes, splitBytesParam, "maximum size per exported file (e.g. `100MB`)")
+
+	command.
+		PersistentFlags().
+		StringSliceVar(&c.params.PartitionBy, partitionByParam, []string{}, "columns used to write the export in `col=value` directories")
+
+	command.
+		PersistentFlags().
+		IntVar(&c.params.MaxOpenFiles, maxOpenFilesParam, 32, "maximum number of files kept open by partitioned exports")
+
+	command.
+		PersistentFlags().
+		StringVar(&c.params.Template, templateParam, "", "go text/template file used by `template` export")
+
+	command.
+		PersistentFlags().
+		BoolVar(&c.params.Overwrite, overwriteParam, false, "replace existing export file once the export succeeds (default)")
+
+	command.
+		PersistentFlags().
+		BoolVar(&c.params.Append, appendParam, false, "append to existing export file, `csv` header is skipped when columns match")
+
+	command.
+		PersistentFlags().
+		BoolVar(&c.params.NoClobber, noClobberParam, false, "fail when the export file already exists")
+
+	command.
+		PersistentFlags().
+		BoolVar(&c.params.Count, countParam, false, "count result rows before exporting to show an accurate progress")
+
+	command.
+		PersistentFlags().
+		DurationVar(&c.params.Timeout, timeoutParam, 0, "cancel the query when it takes longer than the duration (e.g. `30s`)")
+
+	command.
+		PersistentFlags().
+		IntVar(&c.params.MaxRows, maxRowsParam, 1000, "maximum number of rows displayed in the terminal, `0` shows all rows")
+
+	command.
+		PersistentFlags().
+		BoolVar(&c.params.NoPager, noPagerParam, false, "disable paging of results larger than the terminal")
+
+	command.
+		PersistentFlags().
+		StringVarP(&c.params.Output, outputParam, outputShortParam, "table", "format of results printed on stdout [`table`,`csv`,`tsv`,`json`,`jsonl`,`markdown`,`vertical`]")
+
+	command.
+		PersistentFlags().
+		BoolVar(&c.params.Quiet, quietParam, false, "suppress the progress bar")
+
+	command.MarkFlagsMutuallyExclusive(overwriteParam, appendParam, noClobberParam)
+	command.MarkFlagsMutuallyExclusive(queryParam, queryFileParam)
 
 	if c.params.Export != "" && c.params.Type == "" {
 		return nil, fmt.Errorf("failed to validate flag")
@@ -0,0 +1,367 @@
+package csvql
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/filehandler"
+	"adrianolaselva.github.io/csvql/pkg/storage"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+const (
+	commandPrefix = "."
+	timerOn       = "on"
+	timerOff      = "off"
+)
+
+var errExit = errors.New("exit")
+
+// command meta command available in the prompt
+type command struct {
+	name        string
+	usage       string
+	description string
+	run         func(c *csvql, args string) error
+}
+
+// commands list meta commands available in the prompt
+func commands() []command {
+	return []command{
+		{name: ".tables", usage: ".tables", description: "list imported tables", run: (*csvql).commandTables},
+		{name: ".schema", usage: ".schema <table>", description: "show statements used to create the table", run: (*csvql).commandSchema},
+		{name: ".describe", usage: ".describe <table>", description: "list columns of the table", run: (*csvql).commandDescribe},
+		{name: ".index", usage: ".index [<table>(<col1>,<col2>)]", description: "create index on the columns of the table or list indexes", run: (*csvql).commandIndex},
+		{name: ".mode", usage: ".mode table|csv|tsv|json|jsonl|vertical|markdown", description: "change output format", run: (*csvql).commandMode},
+		{name: ".export", usage: ".export <type> <path> <query>", description: "export query result to file", run: (*csvql).commandExport},
+		{name: ".import", usage: ".import <file> [as <table>]", description: "import csv file into table", run: (*csvql).commandImport},
+		{name: ".read", usage: ".read <script.sql>", description: "execute statements from file", run: (*csvql).commandRead},
+		{name: ".maxrows", usage: ".maxrows <n>", description: "limit rows displayed in the terminal, 0 shows all rows", run: (*csvql).commandMaxRows},
+		{name: ".pager", usage: ".pager on|off", description: "page results larger than the terminal", run: (*csvql).commandPager},
+		{name: ".timer", usage: ".timer on|off", description: "show elapsed time of each statement", run: (*csvql).commandTimer},
+		{name: ".set", usage: ".set [<name> <value>]", description: "set parameter bound to :name placeholders or list parameters", run: (*csvql).commandSet},
+		{name: ".unset", usage: ".unset <name>", description: "remove parameter", run: (*csvql).commandUnset},
+		{name: ".help", usage: ".help", description: "show available commands", run: (*csvql).commandHelp},
+		{name: ".exit", usage: ".exit", description: "exit prompt", run: (*csvql).commandExit},
+	}
+}
+
+// isCommand check if line is a meta command
+func isCommand(line string) bool {
+	return strings.HasPrefix(line, commandPrefix)
+}
+
+// executeCommand execute meta command
+func (c *csvql) executeCommand(line string) error {
+	name, args := splitArgument(line)
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd.run(c, args)
+		}
+	}
+
+	return fmt.Errorf("unknown command %s, use .help to list available commands", name)
+}
+
+// commandTables list imported tables
+func (c *csvql) commandTables(_ string) error {
+	return c.printTables()
+}
+
+// printTables print tables of the catalog with their columns
+func (c *csvql) printTables() error {
+	tables, err := c.storage.ListTables()
+	if err != nil {
+		return fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	records := make([][]any, len(tables))
+	for i, t := range tables {
+		records[i] = []any{t.ID, t.Name, t.Columns, t.TotalColumns}
+	}
+
+	return c.printResult(storage.NewRows(resultColumns("id", "name", "columns", "total_columns"), records))
+}
+
+// commandSchema show statements used to create the table
+func (c *csvql) commandSchema(args string) error {
+	tableName, _ := splitArgument(args)
+	if tableName == "" {
+		return fmt.Errorf("usage: .schema <table>")
+	}
+
+	statements, err := c.storage.ShowSchema(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to load schema: %w", err)
+	}
+
+	records := make([][]any, len(statements))
+	for i, statement := range statements {
+		records[i] = []any{statement}
+	}
+
+	return c.printResult(storage.NewRows(resultColumns("sql"), records))
+}
+
+// commandDescribe list columns of the table
+func (c *csvql) commandDescribe(args string) error {
+	tableName, _ := splitArgument(args)
+	if tableName == "" {
+		return fmt.Errorf("usage: .describe <table>")
+	}
+
+	columns, err := c.storage.DescribeTable(tableName)
+	if err != nil {
+		return fmt.Errorf("failed to describe table: %w", err)
+	}
+
+	records := make([][]any, len(columns))
+	for i, col := range columns {
+		var dflt any
+		if col.Default != nil {
+			dflt = *col.Default
+		}
+
+		records[i] = []any{int64(i), col.Name, col.Type, flag(col.NotNull), dflt, flag(col.PrimaryKey)}
+	}
+
+	return c.printResult(storage.NewRows(resultColumns("cid", "name", "type", "notnull", "dflt_value", "pk"), records))
+}
+
+// commandIndex create index on the columns of the table or list indexes
+func (c *csvql) commandIndex(args string) error {
+	if strings.TrimSpace(args) == "" {
+		indexes, err := c.storage.ListIndexes()
+		if err != nil {
+			return fmt.Errorf("failed to list indexes: %w", err)
+		}
+
+		records := make([][]any, len(indexes))
+		for i, index := range indexes {
+			records[i] = []any{index.Name, index.Table, index.SQL}
+		}
+
+		return c.printResult(storage.NewRows(resultColumns("name", "table", "sql"), records))
+	}
+
+	index, err := filehandler.ParseIndex(args)
+	if err != nil {
+		return err
+	}
+
+	return c.storage.CreateIndex(index.Table, index.Columns, false)
+}
+
+// resultColumns columns of a result built from the catalog
+func resultColumns(names ...string) []storage.Column {
+	columns := make([]storage.Column, len(names))
+	for i, name := range names {
+		columns[i] = storage.Column{Name: name}
+	}
+
+	return columns
+}
+
+// flag format boolean attribute of the catalog as 0 or 1
+func flag(value bool) int64 {
+	if value {
+		return 1
+	}
+
+	return 0
+}
+
+// commandMode change output format
+func (c *csvql) commandMode(args string) error {
+	mode, _ := splitArgument(args)
+	if mode == "" {
+		fmt.Println(c.mode)
+		return nil
+	}
+
+	if !isOutputMode(mode) {
+		return fmt.Errorf("invalid mode %s, available modes: %s", mode, strings.Join(outputModes, "|"))
+	}
+
+	c.mode = mode
+
+	return nil
+}
+
+// commandExport export query result to file
+func (c *csvql) commandExport(args string) error {
+	exportType, rest := splitArgument(args)
+	exportPath, query := splitArgument(rest)
+	if exportType == "" || exportPath == "" || query == "" {
+		return fmt.Errorf("usage: .export <type> <path> <query>")
+	}
+
+	return c.exportQuery(query, exportType, exportPath)
+}
+
+// commandImport import csv file into table
+func (c *csvql) commandImport(args string) error {
+	file, rest := splitArgument(args)
+	if file == "" {
+		return fmt.Errorf("usage: .import <file> [as <table>]")
+	}
+
+	keyword, tableName := splitArgument(rest)
+	if keyword != "" && (!strings.EqualFold(keyword, "as") || tableName == "") {
+		return fmt.Errorf("usage: .import <file> [as <table>]")
+	}
+
+	tableName, err := c.fileHandler.ImportFile(file, tableName)
+	_ = c.bar.Clear()
+	if err != nil {
+		return fmt.Errorf("failed to import file %s: %w", file, err)
+	}
+
+	if c.completer != nil {
+		c.completer.Refresh()
+	}
+
+	fmt.Printf("[%s] file imported into table %s\n", file, tableName)
+
+	return nil
+}
+
+// commandRead execute statements from file
+func (c *csvql) commandRead(args string) error {
+	file, _ := splitArgument(args)
+	if file == "" {
+		return fmt.Errorf("usage: .read <script.sql>")
+	}
+
+	script, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("failed to read file %s: %w", file, err)
+	}
+
+	return c.executeScript(string(script))
+}
+
+// commandTimer enable or disable elapsed time of each statement
+func (c *csvql) commandTimer(args string) error {
+	switch value, _ := splitArgument(args); strings.ToLower(value) {
+	case timerOn:
+		c.timer = true
+	case timerOff:
+		c.timer = false
+	default:
+		return fmt.Errorf("usage: .timer on|off")
+	}
+
+	return nil
+}
+
+// commandMaxRows change the limit of rows displayed in the terminal
+func (c *csvql) commandMaxRows(args string) error {
+	value, _ := splitArgument(args)
+	if value == "" {
+		fmt.Println(c.maxRows)
+		return nil
+	}
+
+	maxRows, err := strconv.Atoi(value)
+	if err != nil || maxRows < 0 {
+		return fmt.Errorf("usage: .maxrows <n>")
+	}
+
+	c.maxRows = maxRows
+
+	return nil
+}
+
+// commandPager enable or disable paging of results
+func (c *csvql) commandPager(args string) error {
+	switch value, _ := splitArgument(args); strings.ToLower(value) {
+	case pagerOn:
+		c.pager = true
+	case pagerOff:
+		c.pager = false
+	default:
+		return fmt.Errorf("usage: .pager on|off")
+	}
+
+	return nil
+}
+
+// commandSet set parameter bound to placeholders or list parameters
+func (c *csvql) commandSet(args string) error {
+	name, value := splitArgument(args)
+	if name == "" {
+		names := make([]string, 0, len(c.variables))
+		for n := range c.variables {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+
+		for _, n := range names {
+			fmt.Printf("%s = %s\n", n, c.variables[n])
+		}
+
+		return nil
+	}
+
+	if !storage.IsPlaceholderName(name) {
+		return fmt.Errorf("invalid parameter name %s", name)
+	}
+
+	c.variables[name] = unquote(value)
+
+	return nil
+}
+
+// commandUnset remove parameter
+func (c *csvql) commandUnset(args string) error {
+	name, _ := splitArgument(args)
+	if name == "" {
+		return fmt.Errorf("usage: .unset <name>")
+	}
+
+	delete(c.variables, name)
+
+	return nil
+}
+
+// commandHelp show available commands
+func (c *csvql) commandHelp(_ string) error {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 3, ' ', 0)
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "%s\t%s\n", cmd.usage, cmd.description)
+	}
+
+	if err := w.Flush(); err != nil {
+		return fmt.Errorf("failed to print help: %w", err)
+	}
+
+	return nil
+}
+
+// commandExit exit prompt
+func (c *csvql) commandExit(_ string) error {
+	return errExit
+}
+
+// unquote remove matching single or double quotes around value
+func unquote(value string) string {
+	if len(value) >= 2 && (value[0] == '\'' || value[0] == '"') && value[len(value)-1] == value[0] {
+		return value[1 : len(value)-1]
+	}
+
+	return value
+}
+
+// splitArgument split first argument from the rest of the line
+func splitArgument(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	if i := strings.IndexFunc(line, isSpace); i >= 0 {
+		return line[:i], strings.TrimSpace(line[i:])
+	}
+
+	return line, ""
+}
@@ -0,0 +1,265 @@
+package csvql
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+var sqlKeywords = []string{
+	"ABORT", "ALL", "ALTER", "AND", "AS", "ASC", "BETWEEN", "BY", "CASE", "CAST", "COLLATE", "CREATE", "CROSS",
+	"DELETE", "DESC", "DISTINCT", "DROP", "ELSE", "END", "ESCAPE", "EXCEPT", "EXISTS", "EXPLAIN", "FROM", "FULL",
+	"GLOB", "GROUP", "HAVING", "IN", "INDEX", "INNER", "INSERT", "INTERSECT", "INTO", "IS", "JOIN", "LEFT", "LIKE",
+	"LIMIT", "NOT", "NULL", "OFFSET", "ON", "OR", "ORDER", "OUTER", "OVER", "PARTITION", "PRAGMA", "RECURSIVE",
+	"REPLACE", "RIGHT", "SELECT", "SET", "TABLE", "THEN", "UNION", "UPDATE", "USING", "VALUES", "VIEW", "WHEN",
+	"WHERE", "WINDOW", "WITH",
+}
+
+var sqlFunctions = []string{
+	"abs", "avg", "char", "coalesce", "count", "date", "datetime", "dense_rank", "first_value", "glob", "group_concat",
+	"hex", "ifnull", "iif", "instr", "json", "json_array", "json_extract", "json_group_array", "json_group_object",
+	"json_object", "julianday", "lag", "last_value", "lead", "length", "like", "lower", "ltrim", "max", "min",
+	"ntile", "nullif", "printf", "quote", "random", "rank", "replace", "round", "row_number", "rtrim", "sign",
+	"strftime", "substr", "sum", "time", "total", "trim", "typeof", "unicode", "upper",
+}
+
+var tableReferenceRegex = regexp.MustCompile("(?i)\\b(?:from|join|into|update|table)\\s+[`\"\\[]?([a-zA-Z0-9_]+)[`\"\\]]?(?:\\s+(?:as\\s+)?([a-zA-Z0-9_]+))?")
+
+// completer complete sql keywords, functions, meta commands, tables and columns of the referenced tables
+type completer struct {
+	mx          sync.Mutex
+	loadTables  func() ([]string, error)
+	loadColumns func(string) ([]string, error)
+	statement   func() string
+	tables      []string
+	columns     map[string][]string
+	loaded      bool
+}
+
+func newCompleter(loadTables func() ([]string, error), loadColumns func(string) ([]string, error), statement func() string) *completer {
+	return &completer{loadTables: loadTables, loadColumns: loadColumns, statement: statement}
+}
+
+// Do return candidates completing the word before the cursor
+func (c *completer) Do(line []rune, pos int) ([][]rune, int) {
+	start := pos
+	for start > 0 && isWordRune(line[start-1]) {
+		start--
+	}
+
+	word := string(line[start:pos])
+	before := string(line[:start])
+
+	prefix := word
+	qualifier := ""
+	if i := strings.LastIndex(word, "."); i >= 0 && !(start == 0 && i == 0) {
+		qualifier, prefix = word[:i], word[i+1:]
+	}
+
+	candidates := c.candidates(before, qualifier, prefix)
+
+	result := make([][]rune, 0, len(candidates))
+	for _, candidate := range candidates {
+		result = append(result, []rune(candidate[len(prefix):]+" "))
+	}
+
+	return result, len([]rune(prefix))
+}
+
+// Refresh discard loaded tables and columns, so they are loaded again on the next completion
+func (c *completer) Refresh() {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	c.loaded = false
+	c.tables = nil
+	c.columns = nil
+}
+
+// candidates list words starting with prefix according to the position in the line
+func (c *completer) candidates(before string, qualifier string, prefix string) []string {
+	trimmed := strings.TrimSpace(before)
+	switch {
+	case trimmed == "" && strings.HasPrefix(prefix, commandPrefix):
+		return c.filter(c.commandNames(), prefix)
+	case isCommand(trimmed):
+		return c.filter(c.commandArguments(trimmed), prefix)
+	case qualifier != "":
+		return c.filter(c.columnsOf(c.resolveTable(before, qualifier)), prefix)
+	}
+
+	words := make([]string, 0)
+	words = append(words, c.tablesNames()...)
+	for _, table := range c.referencedTables(c.currentStatement(before)) {
+		words = append(words, c.columnsOf(table)...)
+	}
+
+	words = append(words, c.matchCase(sqlKeywords, prefix)...)
+	words = append(words, c.matchCase(sqlFunctions, prefix)...)
+
+	return c.filter(words, prefix)
+}
+
+// commandNames list meta commands names
+func (c *completer) commandNames() []string {
+	names := make([]string, 0)
+	for _, cmd := range commands() {
+		names = append(names, cmd.name)
+	}
+
+	return names
+}
+
+// commandArguments list candidates for the first argument of meta commands
+func (c *completer) commandArguments(before string) []string {
+	name, args := splitArgument(before)
+	if args != "" {
+		return nil
+	}
+
+	switch name {
+	case ".schema", ".describe", ".index":
+		return c.tablesNames()
+	case ".mode":
+		return outputModes
+	case ".timer":
+		return []string{timerOn, timerOff}
+	case ".pager":
+		return []string{pagerOn, pagerOff}
+	}
+
+	return nil
+}
+
+// currentStatement text of the statement being written, including previous lines not yet executed
+func (c *completer) currentStatement(before string) string {
+	if c.statement == nil {
+		return before
+	}
+
+	return c.statement() + "\n" + before
+}
+
+// resolveTable resolve qualifier as table name or alias of a referenced table
+func (c *completer) resolveTable(before string, qualifier string) string {
+	for _, match := range tableReferenceRegex.FindAllStringSubmatch(c.currentStatement(before), -1) {
+		if strings.EqualFold(match[2], qualifier) {
+			return match[1]
+		}
+	}
+
+	return qualifier
+}
+
+// referencedTables list known tables referenced in the statement
+func (c *completer) referencedTables(statement string) []string {
+	tables := make([]string, 0)
+	for _, match := range tableReferenceRegex.FindAllStringSubmatch(statement, -1) {
+		for _, table := range c.tablesNames() {
+			if strings.EqualFold(table, match[1]) {
+				tables = append(tables, table)
+			}
+		}
+	}
+
+	return tables
+}
+
+// tablesNames list tables of the catalog
+func (c *completer) tablesNames() []string {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if err := c.load(); err != nil {
+		return nil
+	}
+
+	return c.tables
+}
+
+// columnsOf list columns of table
+func (c *completer) columnsOf(table string) []string {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if err := c.load(); err != nil {
+		return nil
+	}
+
+	for name, columns := range c.columns {
+		if strings.EqualFold(name, table) {
+			return columns
+		}
+	}
+
+	return nil
+}
+
+// load load tables and columns once until refreshed
+func (c *completer) load() error {
+	if c.loaded {
+		return nil
+	}
+
+	tables, err := c.loadTables()
+	if err != nil {
+		return fmt.Errorf("failed to load tables: %w", err)
+	}
+
+	columns := map[string][]string{}
+	for _, table := range tables {
+		cols, err := c.loadColumns(table)
+		if err != nil {
+			return fmt.Errorf("failed to load columns of %s: %w", table, err)
+		}
+
+		columns[table] = cols
+	}
+
+	c.tables = tables
+	c.columns = columns
+	c.loaded = true
+
+	return nil
+}
+
+// matchCase convert words to upper case when the prefix is written in upper case
+func (c *completer) matchCase(words []string, prefix string) []string {
+	upper := prefix != "" && strings.ToUpper(prefix) == prefix
+	converted := make([]string, 0, len(words))
+	for _, w := range words {
+		if upper {
+			converted = append(converted, strings.ToUpper(w))
+			continue
+		}
+
+		converted = append(converted, strings.ToLower(w))
+	}
+
+	return converted
+}
+
+// filter keep unique words starting with prefix, ignoring case
+func (c *completer) filter(words []string, prefix string) []string {
+	seen := map[string]bool{}
+	matches := make([]string, 0)
+	for _, w := range words {
+		if seen[w] || len(w) < len(prefix) || !strings.EqualFold(w[:len(prefix)], prefix) {
+			continue
+		}
+
+		seen[w] = true
+		matches = append(matches, w)
+	}
+
+	sort.Strings(matches)
+
+	return matches
+}
+
+// isWordRune check if rune is part of an identifier
+func isWordRune(r rune) bool {
+	return r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
@@ -0,0 +1,49 @@
+package csvql
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestShouldCompleteWithSuccess(t *testing.T) {
+	columns := map[string][]string{
+		"customers": {"id", "name"},
+		"orders":    {"id", "customer_id", "amount"},
+	}
+
+	c := newCompleter(func() ([]string, error) {
+		return []string{"customers", "orders"}, nil
+	}, func(table string) ([]string, error) {
+		return columns[table], nil
+	}, nil)
+
+	tests := []struct {
+		line    string
+		expects []string
+		length  int
+	}{
+		{line: "SEL", expects: []string{"ECT "}, length: 3},
+		{line: "sel", expects: []string{"ect "}, length: 3},
+		{line: "select * from ord", expects: []string{"er ", "ers "}, length: 3},
+		{line: "select am", expects: []string{}, length: 2},
+		{line: "select * from orders where am", expects: []string{"ount "}, length: 2},
+		{line: "select * from orders o where o.cu", expects: []string{"stomer_id "}, length: 2},
+		{line: "select customers.na", expects: []string{"me "}, length: 2},
+		{line: ".sch", expects: []string{"ema "}, length: 4},
+		{line: ".describe cu", expects: []string{"stomers "}, length: 2},
+		{line: ".mode js", expects: []string{"on ", "onl "}, length: 2},
+		{line: "select upp", expects: []string{"er "}, length: 3},
+	}
+
+	for _, test := range tests {
+		candidates, length := c.Do([]rune(test.line), len([]rune(test.line)))
+
+		values := make([]string, 0, len(candidates))
+		for _, candidate := range candidates {
+			values = append(values, string(candidate))
+		}
+
+		assert.Equal(t, test.expects, values, test.line)
+		assert.Equal(t, test.length, length, test.line)
+	}
+}
@@ -2,28 +2,43 @@ package csvql
 
 import (
 	"adrianolaselva.github.io/csvql/internal/exportdata"
+	"adrianolaselva.github.io/csvql/pkg/datasize"
+	exportWriter "adrianolaselva.github.io/csvql/pkg/exportdata"
 	"adrianolaselva.github.io/csvql/pkg/filehandler"
 	csvHandler "adrianolaselva.github.io/csvql/pkg/filehandler/csv"
 	"adrianolaselva.github.io/csvql/pkg/storage"
+	"adrianolaselva.github.io/csvql/pkg/storage/duckdb"
 	"adrianolaselva.github.io/csvql/pkg/storage/sqlite"
-	"database/sql"
+	"context"
 	"errors"
 	"fmt"
 	"github.com/chzyer/readline"
-	"github.com/fatih/color"
-	"github.com/rodaine/table"
 	"github.com/schollz/progressbar/v3"
 	"io"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"sync"
+	"syscall"
+	"time"
 )
 
 const (
-	cliPrompt          = "csvql> "
-	cliInterruptPrompt = "^C"
-	cliEOFPrompt       = "exit"
+	cliPrompt             = "csvql> "
+	cliInterruptPrompt    = "^C"
+	cliEOFPrompt          = "exit"
+	cliContinuationPrompt = "   ...> "
+	historyFileName       = ".csvql_history"
+	historyLimit          = 1000
+	exportDescription     = "[cyan][1/1][reset] exporting data..."
+	exportRefreshRate     = 250 * time.Millisecond
+	sqlCountTemplate      = "select count(*) from (%s\n)"
 )
 
+// ErrInterrupted returned when SIGINT stops the import or the statements, after storage and exports are cleaned up
+var ErrInterrupted = errors.New("interrupted")
+
 type Csvql interface {
 	Run() error
 	Close() error
@@ -32,18 +47,128 @@ type Csvql interface {
 type csvql struct {
 	storage     storage.Storage
 	bar         *progressbar.ProgressBar
+	barWriter   io.Writer
 	params      Params
 	fileHandler filehandler.FileHandler
+	mode        string
+	timer       bool
+	completer   *completer
+	pending     string
+	maxRows     int
+	variables   map[string]string
+	pager       bool
+	mx          sync.Mutex
+	cancel      context.CancelFunc
+	interrupted context.Context
+	interrupt   context.CancelFunc
 }
 
 func New(params Params) (Csvql, error) {
-	sqLiteStorage, err := sqlite.NewSqLiteStorage(params.DataSourceName)
+	if params.Output == "" {
+		params.Output = tableMode
+	}
+
+	if !isOutputMode(params.Output) {
+		return nil, fmt.Errorf("invalid output %s, available outputs: %s", params.Output, strings.Join(outputModes, "|"))
+	}
+
+	if len(params.FileInputs) == 0 {
+		if params.DataSourceName == "" {
+			return nil, fmt.Errorf("at least one file or an existing storage is required")
+		}
+
+		if _, err := os.Stat(params.DataSourceName); err != nil {
+			return nil, fmt.Errorf("failed to open storage %s: %w", params.DataSourceName, err)
+		}
+	}
+
+	if params.Engine == "" {
+		params.Engine = storage.SqliteEngine
+	}
+
+	if !storage.IsEngine(params.Engine) {
+		return nil, fmt.Errorf("invalid engine %s, available engines: %s", params.Engine, strings.Join(storage.Engines, "|"))
+	}
+
+	if params.IfExists == "" {
+		params.IfExists = filehandler.ReplaceIfExists
+	}
+
+	if !filehandler.IsIfExistsMode(params.IfExists) {
+		return nil, fmt.Errorf("invalid if-exists %s, available modes: %s", params.IfExists, strings.Join(filehandler.IfExistsModes, "|"))
+	}
+
+	if params.ImportMode == "" {
+		params.ImportMode = filehandler.InsertMode
+	}
+
+	if params.ImportMode != filehandler.InsertMode && params.ImportMode != filehandler.UpsertMode {
+		return nil, fmt.Errorf("invalid mode %s, available modes: %s|%s", params.ImportMode, filehandler.InsertMode, filehandler.UpsertMode)
+	}
+
+	if params.ImportMode == filehandler.UpsertMode && len(params.PrimaryKey) == 0 {
+		return nil, fmt.Errorf("primary key is required by %s mode", filehandler.UpsertMode)
+	}
+
+	if params.LazyStats {
+		params.Lazy = true
+	}
+
+	if params.Lazy && (len(params.PrimaryKey) > 0 || len(params.Indexes) > 0 || params.AutoIndex || params.Lines > 0) {
+		return nil, errors.New("lazy mode does not support primary keys, indexes and lines")
+	}
+
+	if params.Lazy && (len(params.Select) > 0 || params.Where != "" || params.Sample != "") {
+		return nil, errors.New("lazy mode does not support select, where and sample, filter in the query instead")
+	}
+
+	var sample filehandler.Sample
+	if params.Sample != "" {
+		parsed, err := filehandler.ParseSample(params.Sample)
+		if err != nil {
+			return nil, err
+		}
+		sample = parsed
+	}
+
+	if params.SampleBy != "" && sample.Rows == 0 {
+		return nil, errors.New("sample-by requires a sample with a number of rows, percentages already sample every value proportionally")
+	}
+
+	if params.Lazy && params.IfExists == filehandler.AppendIfExists {
+		return nil, fmt.Errorf("lazy mode does not support %s if-exists", filehandler.AppendIfExists)
+	}
+
+	indexes := make([]filehandler.Index, 0, len(params.Indexes))
+	for _, spec := range params.Indexes {
+		index, err := filehandler.ParseIndex(spec)
+		if err != nil {
+			return nil, err
+		}
+
+		indexes = append(indexes, index)
+	}
+
+	variables, err := parseVariables(params.Variables)
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := openStorage(params)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize storage: %w", err)
 	}
 
+	var barWriter io.Writer = os.Stdout
+	switch {
+	case params.Quiet || exportWriter.IsStdout(params.Export):
+		barWriter = io.Discard
+	case !isTerminal(os.Stdout):
+		barWriter = os.Stderr
+	}
+
 	bar := progressbar.NewOptions(0,
-		progressbar.OptionSetWriter(os.Stdout),
+		progressbar.OptionSetWriter(barWriter),
 		progressbar.OptionEnableColorCodes(true),
 		progressbar.OptionShowBytes(true),
 		progressbar.OptionFullWidth(),
@@ -56,9 +181,39 @@ func New(params Params) (Csvql, error) {
 			BarEnd:        "]",
 		}))
 
-	impData := csvHandler.NewCsvHandler(params.FileInputs, rune(params.Delimiter[0]), bar, sqLiteStorage, params.Lines)
+	impData := csvHandler.NewCsvHandler(params.FileInputs, rune(params.Delimiter[0]), bar, s, filehandler.Options{
+		Lines:      params.Lines,
+		Refresh:    params.Refresh,
+		IfExists:   params.IfExists,
+		PrimaryKey: params.PrimaryKey,
+		Mode:       params.ImportMode,
+		Indexes:    indexes,
+		AutoIndex:  params.AutoIndex,
+		Lazy:       params.Lazy,
+		LazyStats:  params.LazyStats,
+		Select:     params.Select,
+		Where:      params.Where,
+		Sample:     sample,
+		SampleBy:   params.SampleBy,
+		Seed:       params.Seed,
+		Persistent: params.DataSourceName != "",
+	})
 
-	return &csvql{params: params, bar: bar, fileHandler: impData, storage: sqLiteStorage}, nil
+	interrupted, interrupt := context.WithCancel(context.Background())
+
+	return &csvql{
+		params:      params,
+		bar:         bar,
+		barWriter:   barWriter,
+		fileHandler: impData,
+		storage:     s,
+		mode:        params.Output,
+		maxRows:     params.MaxRows,
+		variables:   variables,
+		pager:       !params.NoPager,
+		interrupted: interrupted,
+		interrupt:   interrupt,
+	}, nil
 }
 
 // Run import file content and run command
@@ -67,36 +222,133 @@ func (c *csvql) Run() error {
 		_ = bar.Clear()
 	}(c.bar)
 
+	stop := c.captureInterrupt()
+	defer stop()
+
 	if err := c.fileHandler.Import(); err != nil {
+		if c.interrupted.Err() != nil {
+			return ErrInterrupted
+		}
+
 		return fmt.Errorf("failed to import data %w", err)
 	}
+
+	_ = c.bar.Clear()
+	for _, summary := range c.fileHandler.Summaries() {
+		if summary.Skipped {
+			fmt.Fprintf(infoWriter(), "[%s] skipped %s, unchanged since the last import\n", summary.Table, summary.Path)
+			continue
+		}
+
+		fmt.Fprintf(infoWriter(), "[%s] %d inserted, %d updated, %d unchanged\n", summary.Table, summary.Inserted, summary.Updated, summary.Unchanged)
+	}
 	defer func(fileHandler filehandler.FileHandler) {
 		_ = fileHandler.Close()
 	}(c.fileHandler)
 
-	rows, err := c.storage.ShowTables()
-	if err != nil {
-		return fmt.Errorf("failed to list tables: %w", err)
+	if exportWriter.IsStdout(c.params.Export) || !isTerminal(os.Stdout) {
+		return c.execute(stop)
 	}
 
-	if err := c.printResult(rows); err != nil {
+	if err := c.printTables(); err != nil {
 		return fmt.Errorf("failed to print tables: %w", err)
 	}
 
-	return c.execute()
+	return c.execute(stop)
 }
 
-// execute execution after data import
-func (c *csvql) execute() error {
-	switch {
-	case c.params.Query != "" && c.params.Export == "":
-		return c.executeQuery(c.params.Query)
-	case c.params.Query != "" && c.params.Export != "":
-		return c.executeQueryAndExport(c.params.Query)
-	default:
-		if err := c.initializePrompt(); err != nil {
-			return err
+// execute execution after data import, the prompt handles SIGINT itself once stopInterrupt is called
+func (c *csvql) execute(stopInterrupt func()) error {
+	script, err := c.script()
+	if err != nil {
+		return err
+	}
+
+	if script == "" {
+		stopInterrupt()
+		if c.interrupted.Err() != nil {
+			return ErrInterrupted
 		}
+
+		return c.initializePrompt()
+	}
+
+	if c.params.Export != "" {
+		return c.executeQueryAndExport(script)
+	}
+
+	return c.executeScript(script)
+}
+
+// parseVariables parse variables in the `name=value` format
+func parseVariables(values []string) (map[string]string, error) {
+	variables := make(map[string]string)
+	for _, value := range values {
+		name, v, ok := strings.Cut(value, "=")
+		if !ok || !storage.IsPlaceholderName(name) {
+			return nil, fmt.Errorf("invalid param %s, expected name=value", value)
+		}
+
+		variables[name] = v
+	}
+
+	return variables, nil
+}
+
+// script statements passed by query param or read from the query file
+func (c *csvql) script() (string, error) {
+	if c.params.QueryFile == "" {
+		return c.params.Query, nil
+	}
+
+	script, err := os.ReadFile(c.params.QueryFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read file %s: %w", c.params.QueryFile, err)
+	}
+
+	return string(script), nil
+}
+
+// executeScript execute statements of the script
+func (c *csvql) executeScript(script string) error {
+	return c.executeStatements(scriptStatements(script))
+}
+
+// executeStatements execute statements in order printing their results separated by a blank line
+func (c *csvql) executeStatements(statements []string) error {
+	return c.runStatements(statements, func(i int, statement string) error {
+		if i > 0 {
+			fmt.Println()
+		}
+
+		return c.executeQuery(statement)
+	})
+}
+
+// runStatements run statements in order, stopping at the first error unless continue on error is enabled and on
+// SIGINT between statements
+func (c *csvql) runStatements(statements []string, run func(i int, statement string) error) error {
+	failures := 0
+	for i, statement := range statements {
+		if c.interrupted.Err() != nil {
+			return ErrInterrupted
+		}
+
+		err := run(i, statement)
+		if err == nil {
+			continue
+		}
+
+		if !c.params.ContinueOnError {
+			return fmt.Errorf("statement %d: %w", i+1, err)
+		}
+
+		failures++
+		fmt.Fprintf(os.Stderr, "statement %d: %s\n", i+1, err.Error())
+	}
+
+	if failures > 0 {
+		return fmt.Errorf("%d of %d statements failed", failures, len(statements))
 	}
 
 	return nil
@@ -111,13 +363,19 @@ func (c *csvql) Close() error {
 }
 
 func (c *csvql) initializePrompt() error {
+	c.completer = newCompleter(c.catalogTables, c.catalogColumns, func() string {
+		return c.pending
+	})
+
 	l, err := readline.NewEx(&readline.Config{
-		Prompt:          cliPrompt,
-		InterruptPrompt: cliInterruptPrompt,
-		EOFPrompt:       cliEOFPrompt,
-		AutoComplete: readline.SegmentFunc(func(i [][]rune, i2 int) [][]rune {
-			return nil
-		}),
+		Prompt:                 cliPrompt,
+		InterruptPrompt:        cliInterruptPrompt,
+		EOFPrompt:              cliEOFPrompt,
+		AutoComplete:           c.completer,
+		HistoryFile:            historyFile(),
+		HistoryLimit:           historyLimit,
+		HistorySearchFold:      true,
+		DisableAutoSaveHistory: true,
 	})
 	if err != nil {
 		return fmt.Errorf("failed to initialize cli: %w", err)
@@ -127,15 +385,18 @@ func (c *csvql) initializePrompt() error {
 		_ = l.Close()
 	}(l)
 
-	l.CaptureExitSignal()
+	stop := c.captureSignals(l)
+	defer stop()
 
 	for {
 		line, err := l.Readline()
 		if errors.Is(err, readline.ErrInterrupt) {
-			if len(line) == 0 {
+			if len(line) == 0 && c.pending == "" {
 				break
 			}
 
+			c.pending = ""
+			l.SetPrompt(cliPrompt)
 			continue
 		}
 
@@ -143,91 +404,579 @@ func (c *csvql) initializePrompt() error {
 			break
 		}
 
-		line = strings.TrimSpace(line)
-		if err := c.executeQuery(line); err != nil {
-			fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		if c.pending == "" && isCommand(strings.TrimSpace(line)) {
+			line = strings.TrimSpace(line)
+			_ = l.SaveHistory(line)
+
+			err := c.executeCommand(line)
+			if errors.Is(err, errExit) {
+				break
+			}
+
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			}
+
+			continue
 		}
+
+		statements, remainder := splitStatements(strings.TrimSpace(c.pending + "\n" + line))
+		c.pending = remainder
+
+		for _, statement := range statements {
+			_ = l.SaveHistory(historyEntry(statement))
+			if err := c.executeQuery(statement); err != nil {
+				fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+			}
+		}
+
+		if c.pending == "" {
+			l.SetPrompt(cliPrompt)
+			continue
+		}
+
+		l.SetPrompt(cliContinuationPrompt)
 	}
 
 	return nil
 }
 
+// historyEntry statement collapsed in a single line with its terminator
+func historyEntry(statement string) string {
+	statement, vertical := cutVerticalTerminator(statement)
+	entry := strings.Join(strings.Fields(statement), " ")
+	if vertical {
+		return entry + verticalTerminator
+	}
+
+	return entry + ";"
+}
+
+// captureInterrupt cancel the statement being executed on SIGINT, when no statement is running the import and the
+// remaining statements are stopped so that Run returns ErrInterrupted through the usual cleanup, stop is idempotent
+func (c *csvql) captureInterrupt() func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt)
+
+	go func() {
+		for range signals {
+			if !c.cancelQuery() {
+				c.interrupt()
+				c.fileHandler.Cancel()
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(signals)
+		})
+	}
+}
+
+// captureSignals cancel the statement being executed on SIGINT and close the prompt on SIGTERM
+func (c *csvql) captureSignals(l *readline.Instance) func() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		for sig := range signals {
+			if sig == os.Interrupt {
+				c.cancelQuery()
+				continue
+			}
+
+			_ = l.Close()
+		}
+	}()
+
+	return func() {
+		signal.Stop(signals)
+		close(signals)
+	}
+}
+
+// historyFile path of the file keeping prompt history across sessions
+func historyFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+
+	return filepath.Join(home, historyFileName)
+}
+
+// catalogTables list tables of the catalog
+func (c *csvql) catalogTables() ([]string, error) {
+	tables, err := c.storage.ListTables()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	names := make([]string, len(tables))
+	for i, t := range tables {
+		names[i] = t.Name
+	}
+
+	return names, nil
+}
+
+// catalogColumns list columns of table
+func (c *csvql) catalogColumns(tableName string) ([]string, error) {
+	columns, err := c.storage.DescribeTable(tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe table: %w", err)
+	}
+
+	names := make([]string, len(columns))
+	for i, col := range columns {
+		names[i] = col.Name
+	}
+
+	return names, nil
+}
+
 // executeQueryAndExport execute query and export
-func (c *csvql) executeQueryAndExport(line string) error {
-	c.bar.Reset()
-	c.bar.ChangeMax(c.fileHandler.Lines())
+func (c *csvql) executeQueryAndExport(script string) error {
+	statements := scriptStatements(script)
+	if len(statements) == 0 {
+		return fmt.Errorf("no statement to export")
+	}
+
+	last := len(statements) - 1
+	if last > 0 {
+		err := c.runStatements(statements[:last], func(_ int, statement string) error {
+			return c.discardQuery(statement)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	if c.interrupted.Err() != nil {
+		return ErrInterrupted
+	}
+
+	return c.exportQuery(statements[last], c.params.Type, c.params.Export)
+}
+
+// exportQuery execute query and export result using the export type
+func (c *csvql) exportQuery(line string, exportType string, exportPath string) error {
+	start := time.Now()
+	ctx, cancel := c.queryContext()
+	defer cancel()
+
+	if err := c.export(ctx, line, exportType, exportPath); err != nil {
+		return c.queryError(ctx, err, start)
+	}
+
+	return nil
+}
+
+// export export query result, stopping when the context is done
+func (c *csvql) export(ctx context.Context, line string, exportType string, exportPath string) error {
+	args, err := c.bindArgs(line)
+	if err != nil {
+		return err
+	}
+
+	total, err := c.countRows(ctx, line, args)
+	if err != nil {
+		return err
+	}
+
+	bar := c.newExportBar(total)
 	defer func(bar *progressbar.ProgressBar) {
-		_ = bar.Finish()
-	}(c.bar)
+		_ = bar.Clear()
+	}(bar)
 
-	rows, err := c.storage.Query(line)
+	rows, err := c.storage.QueryContext(ctx, line, args...)
 	if err != nil {
-		return fmt.Errorf("failed to execute query: %w", err)
+		return err
 	}
-	defer func(rows *sql.Rows) {
+	defer func(rows storage.Rows) {
 		_ = rows.Close()
 	}(rows)
 
-	export, err := exportdata.NewExport(c.params.Type, rows, c.params.Export, c.bar)
+	export, err := exportdata.NewExport(exportType, rows, exportPath, bar, exportdata.Options{
+		Pretty:       c.params.Pretty,
+		GroupBy:      c.params.GroupBy,
+		SplitRows:    c.params.SplitRows,
+		SplitBytes:   int64(c.params.SplitBytes),
+		PartitionBy:  c.params.PartitionBy,
+		MaxOpenFiles: c.params.MaxOpenFiles,
+		Template:     c.params.Template,
+		Mode:         c.exportMode(),
+	})
 	if err != nil {
 		return fmt.Errorf("failed to export: %w", err)
 	}
 
-	if err := export.Export(); err != nil {
+	stop := c.watchExport(export, bar)
+	err = export.Export()
+	stop()
+
+	if err != nil {
+		_ = export.Close()
 		return fmt.Errorf("failed to export data: %w", err)
 	}
 
-	_ = c.bar.Clear()
+	if err := export.Close(); err != nil {
+		return fmt.Errorf("failed to export data: %w", err)
+	}
+
+	_ = bar.Clear()
+
+	if exportWriter.IsStdout(exportPath) {
+		return nil
+	}
+
+	for _, file := range export.Files() {
+		fmt.Printf("[%s] file successfully exported\n", file)
+	}
 
-	fmt.Printf("[%s] file successfully exported\n", c.params.Export)
+	fmt.Printf("%d rows exported (%s written)\n", export.Rows(), datasize.Format(export.Bytes()))
 
 	return nil
 }
 
+// countRows count rows returned by the query when pre-count is enabled, otherwise returns -1
+func (c *csvql) countRows(ctx context.Context, line string, args []any) (int64, error) {
+	if !c.params.Count {
+		return -1, nil
+	}
+
+	rows, err := c.storage.QueryContext(ctx, fmt.Sprintf(sqlCountTemplate, strings.TrimRight(strings.TrimSpace(line), "; \t\n")), args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rows: %w", err)
+	}
+	defer func(rows storage.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var total int64
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return 0, fmt.Errorf("failed to count rows: %w", err)
+		}
+
+		total, _ = values[0].(int64)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to count rows: %w", err)
+	}
+
+	return total, nil
+}
+
+// newExportBar build export progress, an indeterminate spinner when the total of rows is unknown
+func (c *csvql) newExportBar(total int64) *progressbar.ProgressBar {
+	return progressbar.NewOptions64(total,
+		progressbar.OptionSetWriter(c.barWriter),
+		progressbar.OptionEnableColorCodes(true),
+		progressbar.OptionShowCount(),
+		progressbar.OptionShowIts(),
+		progressbar.OptionSetItsString("rows"),
+		progressbar.OptionSpinnerType(14),
+		progressbar.OptionFullWidth(),
+		progressbar.OptionSetDescription(exportDescription),
+		progressbar.OptionSetTheme(progressbar.Theme{
+			Saucer:        "[green]=[reset]",
+			SaucerHead:    "[green]>[reset]",
+			SaucerPadding: " ",
+			BarStart:      "[",
+			BarEnd:        "]",
+		}))
+}
+
+// watchExport refresh bytes written in the progress description until the returned function is called
+func (c *csvql) watchExport(export exportWriter.Export, bar *progressbar.ProgressBar) func() {
+	done := make(chan struct{})
+	wg := new(sync.WaitGroup)
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+		ticker := time.NewTicker(exportRefreshRate)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				bar.Describe(fmt.Sprintf("%s %s written", exportDescription, datasize.Format(export.Bytes())))
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
+
+// exportMode resolve behavior when the export path already exists
+func (c *csvql) exportMode() exportWriter.WriteMode {
+	switch {
+	case c.params.Append:
+		return exportWriter.AppendMode
+	case c.params.NoClobber:
+		return exportWriter.NoClobberMode
+	default:
+		return exportWriter.OverwriteMode
+	}
+}
+
 func (c *csvql) executeQuery(line string) error {
-	rows, err := c.storage.Query(line)
+	start := time.Now()
+	ctx, cancel := c.queryContext()
+	defer cancel()
+
+	mode := c.mode
+	line, vertical := cutVerticalTerminator(line)
+	if vertical {
+		mode = verticalMode
+	}
+
+	args, err := c.bindArgs(line)
 	if err != nil {
-		return fmt.Errorf("failed to execute query: %w", err)
+		return err
+	}
+
+	if !returnsRows(line) {
+		return c.executeStatement(ctx, line, args, start)
 	}
-	defer func(rows *sql.Rows) {
+
+	rows, err := c.storage.QueryContext(ctx, line, args...)
+	if err != nil {
+		return c.queryError(ctx, err, start)
+	}
+	defer func(rows storage.Rows) {
 		_ = rows.Close()
 	}(rows)
 
-	return c.printResult(rows)
+	if err := c.printRows(rows, mode); err != nil {
+		return c.queryError(ctx, err, start)
+	}
+
+	if c.timer {
+		fmt.Printf("Run Time: %s\n", time.Since(start))
+	}
+
+	return nil
 }
 
-func (c *csvql) printResult(rows *sql.Rows) error {
-	columns, err := rows.Columns()
+// discardQuery execute statement without printing its result, used before the exported statement to keep
+// the export output clean
+func (c *csvql) discardQuery(line string) error {
+	start := time.Now()
+	ctx, cancel := c.queryContext()
+	defer cancel()
+
+	line, _ = cutVerticalTerminator(line)
+	args, err := c.bindArgs(line)
 	if err != nil {
-		return fmt.Errorf("failed to load columns: %w", err)
+		return err
 	}
 
-	cols := make([]interface{}, 0)
-	for _, c := range columns {
-		cols = append(cols, c)
+	if !returnsRows(line) {
+		if _, err := c.storage.ExecContext(ctx, line, args...); err != nil {
+			return c.queryError(ctx, err, start)
+		}
+
+		return nil
 	}
 
-	tbl := table.New(cols...).
-		WithHeaderFormatter(color.New(color.FgGreen, color.Underline).SprintfFunc()).
-		WithFirstColumnFormatter(color.New(color.FgYellow).SprintfFunc()).
-		WithWriter(os.Stdout)
+	rows, err := c.storage.QueryContext(ctx, line, args...)
+	if err != nil {
+		return c.queryError(ctx, err, start)
+	}
+	defer func(rows storage.Rows) {
+		_ = rows.Close()
+	}(rows)
 
 	for rows.Next() {
-		values := make([]interface{}, len(columns))
-		pointers := make([]interface{}, len(columns))
-		for i := range values {
-			pointers[i] = &values[i]
+	}
+
+	if err := rows.Err(); err != nil {
+		return c.queryError(ctx, err, start)
+	}
+
+	return nil
+}
+
+// executeStatement execute statement that does not return rows, printing the number of rows changed by DML
+func (c *csvql) executeStatement(ctx context.Context, line string, args []any, start time.Time) error {
+	affected, err := c.storage.ExecContext(ctx, line, args...)
+	if err != nil {
+		return c.queryError(ctx, err, start)
+	}
+
+	if c.completer != nil {
+		c.completer.Refresh()
+	}
+
+	w := infoWriter()
+	if changesRows(line) {
+		fmt.Fprintf(w, "%d rows affected\n", affected)
+	} else {
+		fmt.Fprintln(w, "OK")
+	}
+
+	if c.timer {
+		fmt.Printf("Run Time: %s\n", time.Since(start))
+	}
+
+	return nil
+}
+
+// bindArgs params of the variables referenced by the statement placeholders
+func (c *csvql) bindArgs(line string) ([]any, error) {
+	args := make([]any, 0)
+	for _, name := range storage.Placeholders(line) {
+		value, ok := c.variables[name]
+		if !ok {
+			return nil, fmt.Errorf("parameter %s is not defined, use --param %s=value or .set %s value", name, name, name)
 		}
 
-		if err := rows.Scan(pointers...); err != nil {
-			return fmt.Errorf("failed to read row: %w", err)
+		args = append(args, storage.Param{Name: name, Value: value})
+	}
+
+	return args, nil
+}
+
+// openStorage open the storage of the engine, sqlite in memory spills to a temporary file when the input exceeds
+// the memory limit
+func openStorage(params Params) (storage.Storage, error) {
+	if params.Engine == storage.DuckDbEngine {
+		return duckdb.NewDuckDbStorage(params.DataSourceName)
+	}
+
+	if params.DataSourceName != "" || params.Lazy || params.MemoryLimit <= 0 {
+		return sqlite.NewSqLiteStorage(params.DataSourceName)
+	}
+
+	size := inputSize(params.FileInputs)
+	if size <= int64(params.MemoryLimit) {
+		return sqlite.NewSqLiteStorage(params.DataSourceName)
+	}
+
+	if !params.Quiet {
+		fmt.Fprintf(os.Stderr, "input of %s exceeds the memory limit of %s, using a temporary storage file\n",
+			datasize.Format(size), datasize.Format(int64(params.MemoryLimit)))
+	}
+
+	return sqlite.NewTempSqLiteStorage(int64(params.MemoryLimit))
+}
+
+// inputSize total size of the files, missing files are reported by the import
+func inputSize(files []string) int64 {
+	var size int64
+	for _, file := range files {
+		if info, err := os.Stat(file); err == nil {
+			size += info.Size()
 		}
+	}
+
+	return size
+}
 
-		tbl.AddRow(values...)
+// infoWriter writer of informative messages, stderr when stdout is not a terminal to keep results apart
+func infoWriter() io.Writer {
+	if isTerminal(os.Stdout) {
+		return os.Stdout
 	}
 
-	_ = c.bar.Clear()
-	tbl.Print()
+	return os.Stderr
+}
 
-	return nil
+// queryContext build the context of a statement, canceled by SIGINT or when the timeout expires
+func (c *csvql) queryContext() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(c.interrupted)
+	timeoutCancel := context.CancelFunc(func() {})
+	if c.params.Timeout > 0 {
+		ctx, timeoutCancel = context.WithTimeout(ctx, c.params.Timeout)
+	}
+
+	c.mx.Lock()
+	c.cancel = cancel
+	c.mx.Unlock()
+
+	return ctx, func() {
+		c.mx.Lock()
+		c.cancel = nil
+		c.mx.Unlock()
+
+		timeoutCancel()
+		cancel()
+	}
+}
+
+// cancelQuery cancel the statement being executed, returns false when no statement is running
+func (c *csvql) cancelQuery() bool {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	if c.cancel == nil {
+		return false
+	}
+
+	c.cancel()
+
+	return true
+}
+
+// queryError report elapsed time when the statement was canceled or timed out
+func (c *csvql) queryError(ctx context.Context, err error, start time.Time) error {
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("query timed out after %s", time.Since(start).Round(time.Millisecond))
+	case errors.Is(ctx.Err(), context.Canceled):
+		return fmt.Errorf("query canceled after %s", time.Since(start).Round(time.Millisecond))
+	}
+
+	return err
+}
+
+// printResult print rows using the current output mode
+func (c *csvql) printResult(rows storage.Rows) error {
+	return c.printRows(rows, c.mode)
+}
+
+// printRows print rows using the output mode, limited and paged when stdout is a terminal
+func (c *csvql) printRows(rows storage.Rows, mode string) error {
+	columns := storage.ColumnNames(rows.Columns())
+	width, _, ok := terminalSize()
+	if !ok {
+		return c.render(os.Stdout, mode, columns, rows, 0, 0)
+	}
+
+	p := c.newPager()
+	if p == nil {
+		return c.render(os.Stdout, mode, columns, rows, width, c.maxRows)
+	}
+
+	if err := c.render(p, mode, columns, rows, width, c.maxRows); err != nil {
+		_ = p.Close()
+		return err
+	}
+
+	return p.Close()
+}
+
+// render write rows in the output mode
+func (c *csvql) render(w io.Writer, mode string, columns []string, rows storage.Rows, width int, maxRows int) error {
+	switch mode {
+	case verticalMode:
+		return c.printVertical(w, columns, rows, maxRows)
+	case csvMode, tsvMode, jsonMode, jsonlMode, markdownMode:
+		return c.printEncoded(w, mode, columns, rows, maxRows)
+	default:
+		return c.printTable(w, columns, rows, width, maxRows)
+	}
 }
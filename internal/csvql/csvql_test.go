@@ -0,0 +1,162 @@
+package csvql
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/datasize"
+	"adrianolaselva.github.io/csvql/pkg/storage/sqlite"
+	"context"
+	"fmt"
+	"github.com/schollz/progressbar/v3"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestCsvql csvql with an in memory storage holding a rows table with the given number of rows
+func newTestCsvql(t *testing.T, params Params, total int) *csvql {
+	s, err := sqlite.NewSqLiteStorage("")
+	assert.NoError(t, err)
+	t.Cleanup(func() {
+		_ = s.Close()
+	})
+
+	assert.NoError(t, s.BuildStructure("rows", []string{"id"}))
+	for i := 1; i <= total; i++ {
+		assert.NoError(t, s.InsertRow("rows", []string{"`id`"}, []any{fmt.Sprintf("%d", i)}))
+	}
+
+	if params.Output == "" {
+		params.Output = csvMode
+	}
+
+	interrupted, interrupt := context.WithCancel(context.Background())
+	t.Cleanup(interrupt)
+
+	return &csvql{
+		storage:     s,
+		params:      params,
+		bar:         progressbar.NewOptions(0, progressbar.OptionSetWriter(io.Discard)),
+		barWriter:   io.Discard,
+		mode:        params.Output,
+		variables:   map[string]string{},
+		interrupted: interrupted,
+		interrupt:   interrupt,
+	}
+}
+
+func TestShouldCountRowsWithSuccess(t *testing.T) {
+	tests := []struct {
+		query   string
+		expects int64
+	}{
+		{query: "select * from rows;", expects: 3},
+		{query: "select * from rows where id > '1' -- skip the first row", expects: 2},
+		{query: "select * from rows /* all rows */ ;", expects: 3},
+	}
+
+	c := newTestCsvql(t, Params{Count: true}, 3)
+	for _, test := range tests {
+		total, err := c.countRows(context.Background(), test.query, nil)
+		assert.NoError(t, err, test.query)
+		assert.Equal(t, test.expects, total, test.query)
+	}
+}
+
+func TestShouldCancelQueryWithTimeoutWithSuccess(t *testing.T) {
+	c := newTestCsvql(t, Params{Timeout: time.Hour}, 0)
+	assert.False(t, c.cancelQuery())
+
+	ctx, cancel := c.queryContext()
+	assert.True(t, c.cancelQuery())
+	assert.ErrorIs(t, ctx.Err(), context.Canceled)
+
+	cancel()
+	assert.False(t, c.cancelQuery())
+}
+
+func TestShouldExportLastStatementToStdoutWithSuccess(t *testing.T) {
+	c := newTestCsvql(t, Params{Export: "-", Type: csvMode}, 3)
+
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() {
+		os.Stdout = stdout
+	}()
+
+	err = c.executeQueryAndExport("select * from rows; delete from rows where id = '1'; select id from rows;")
+	os.Stdout = stdout
+	_ = w.Close()
+	assert.NoError(t, err)
+
+	out, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Equal(t, "id\n2\n3\n", string(out))
+}
+
+func TestShouldStopStatementsWhenInterruptedWithSuccess(t *testing.T) {
+	dir := t.TempDir()
+	c := newTestCsvql(t, Params{Export: filepath.Join(dir, "rows.csv"), Type: csvMode}, 3)
+	c.interrupt()
+
+	err := c.executeQueryAndExport("delete from rows; select * from rows;")
+	assert.ErrorIs(t, err, ErrInterrupted)
+
+	rows, err := c.storage.Query("select count(*) from rows;")
+	assert.NoError(t, err)
+	assert.True(t, rows.Next())
+	values, err := rows.Values()
+	assert.NoError(t, err)
+	assert.NoError(t, rows.Close())
+	assert.Equal(t, []any{int64(3)}, values)
+
+	entries, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+}
+
+func TestShouldSpillLargeInputsToTemporaryStorageWithSuccess(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMPDIR", dir)
+
+	file := filepath.Join(dir, "rows.csv")
+	assert.NoError(t, os.WriteFile(file, []byte(strings.Repeat("1\n", 1024)), 0o644))
+
+	tests := []struct {
+		limit datasize.Size
+		spill bool
+	}{
+		{limit: 4096},
+		{limit: 1024, spill: true},
+	}
+
+	for _, test := range tests {
+		s, err := openStorage(Params{FileInputs: []string{file}, MemoryLimit: test.limit, Quiet: true})
+		assert.NoError(t, err)
+
+		rows, err := s.Query("select file from pragma_database_list where name = 'main';")
+		assert.NoError(t, err)
+
+		assert.True(t, rows.Next())
+		values, err := rows.Values()
+		assert.NoError(t, err)
+		assert.NoError(t, rows.Close())
+
+		spillFile := values[0].(string)
+		if !test.spill {
+			assert.Empty(t, spillFile)
+			assert.NoError(t, s.Close())
+			continue
+		}
+
+		assert.Equal(t, dir, filepath.Dir(spillFile))
+		assert.FileExists(t, spillFile)
+
+		assert.NoError(t, s.Close())
+		assert.NoFileExists(t, spillFile)
+	}
+}
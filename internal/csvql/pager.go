@@ -0,0 +1,219 @@
+package csvql
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/chzyer/readline"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	pagerEnv         = "PAGER"
+	pagerPrompt      = "-- more (enter to continue, q to quit) --"
+	pagerQuit        = "q"
+	pagerClearPrompt = "\033[1A\033[2K\r"
+	pagerOn          = "on"
+	pagerOff         = "off"
+)
+
+// isTerminal check if file is attached to a terminal
+func isTerminal(f *os.File) bool {
+	return readline.IsTerminal(int(f.Fd()))
+}
+
+// terminalSize width and height of the terminal attached to stdout, ok is false when stdout is not a terminal
+func terminalSize() (int, int, bool) {
+	if !isTerminal(os.Stdout) {
+		return 0, 0, false
+	}
+
+	width, height, err := readline.GetSize(int(os.Stdout.Fd()))
+	if err != nil || width <= 0 || height <= 0 {
+		return 0, 0, false
+	}
+
+	return width, height, true
+}
+
+// pager writer paging content larger than the terminal, lines are held until they exceed one screen and then
+// streamed to $PAGER or to the internal pager
+type pager struct {
+	out     io.Writer
+	input   io.Reader
+	command string
+	height  int
+	held    bytes.Buffer
+	started bool
+	quit    bool
+	lines   int
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+}
+
+// newPager pager of the terminal attached to stdout, nil when paging is disabled or stdout is not a terminal
+func (c *csvql) newPager() *pager {
+	_, height, ok := terminalSize()
+	if !c.pager || !ok {
+		return nil
+	}
+
+	return &pager{
+		out:     os.Stdout,
+		input:   os.Stdin,
+		command: strings.TrimSpace(os.Getenv(pagerEnv)),
+		height:  height,
+	}
+}
+
+// Write hold content until it exceeds the terminal height, then page it
+func (p *pager) Write(content []byte) (int, error) {
+	switch {
+	case p.quit:
+		return len(content), nil
+	case p.stdin != nil:
+		if _, err := p.stdin.Write(content); err != nil {
+			p.quit = true
+		}
+
+		return len(content), nil
+	case p.started:
+		return len(content), p.writePages(content)
+	}
+
+	p.held.Write(content)
+	if bytes.Count(p.held.Bytes(), []byte("\n")) < p.height {
+		return len(content), nil
+	}
+
+	return len(content), p.start()
+}
+
+// Close write content that fits in the terminal and wait for the external pager to finish
+func (p *pager) Close() error {
+	if !p.started {
+		_, err := p.out.Write(p.held.Bytes())
+		return err
+	}
+
+	if p.cmd == nil {
+		return nil
+	}
+
+	_ = p.stdin.Close()
+	if err := p.cmd.Wait(); err != nil {
+		return fmt.Errorf("failed to run pager %s: %w", p.command, err)
+	}
+
+	return nil
+}
+
+// start page the held content through the external pager, or the internal one when $PAGER is not set
+func (p *pager) start() error {
+	p.started = true
+	held := p.held.Bytes()
+	if p.command == "" {
+		return p.writePages(held)
+	}
+
+	p.cmd = exec.Command("sh", "-c", p.command)
+	p.cmd.Stdout = os.Stdout
+	p.cmd.Stderr = os.Stderr
+
+	stdin, err := p.cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to run pager %s: %w", p.command, err)
+	}
+
+	if err := p.cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run pager %s: %w", p.command, err)
+	}
+
+	p.stdin = stdin
+	_, err = p.Write(held)
+
+	return err
+}
+
+// writePages write content line by line, waiting for input before each page after the first one
+func (p *pager) writePages(content []byte) error {
+	lines := p.height - 1
+	if lines <= 0 {
+		lines = 1
+	}
+
+	for len(content) > 0 && !p.quit {
+		if p.lines >= lines {
+			if err := p.prompt(); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		end := bytes.IndexByte(content, '\n') + 1
+		if end == 0 {
+			end = len(content)
+		} else {
+			p.lines++
+		}
+
+		if _, err := p.out.Write(content[:end]); err != nil {
+			return fmt.Errorf("failed to print page: %w", err)
+		}
+
+		content = content[end:]
+	}
+
+	return nil
+}
+
+// prompt wait for input to show the next page, q quits
+func (p *pager) prompt() error {
+	if _, err := fmt.Fprint(p.out, pagerPrompt); err != nil {
+		return fmt.Errorf("failed to print page: %w", err)
+	}
+
+	answer, err := readAnswer(p.input)
+	if err != nil {
+		_, _ = fmt.Fprintln(p.out)
+		p.quit = true
+		return nil
+	}
+
+	if _, err := fmt.Fprint(p.out, pagerClearPrompt); err != nil {
+		return fmt.Errorf("failed to print page: %w", err)
+	}
+
+	p.lines = 0
+	p.quit = strings.EqualFold(strings.TrimSpace(answer), pagerQuit)
+
+	return nil
+}
+
+// pageContent write content in pages of the given number of lines, waiting for input between pages
+func pageContent(w io.Writer, input io.Reader, content []byte, lines int) error {
+	p := &pager{out: w, input: input, height: lines + 1, started: true}
+	return p.writePages(content)
+}
+
+// readAnswer read a single line from input without buffering beyond it
+func readAnswer(input io.Reader) (string, error) {
+	var line strings.Builder
+	buf := make([]byte, 1)
+	for {
+		n, err := input.Read(buf)
+		if n > 0 {
+			if buf[0] == '\n' {
+				return line.String(), nil
+			}
+			line.WriteByte(buf[0])
+		}
+
+		if err != nil {
+			return line.String(), err
+		}
+	}
+}
@@ -0,0 +1,347 @@
+package csvql
+
+import (
+	"adrianolaselva.github.io/csvql/internal/exportdata"
+	"adrianolaselva.github.io/csvql/pkg/storage"
+	"bytes"
+	"fmt"
+	"github.com/fatih/color"
+	"github.com/rodaine/table"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	tableMode           = "table"
+	csvMode             = "csv"
+	tsvMode             = "tsv"
+	jsonMode            = "json"
+	jsonlMode           = "jsonl"
+	verticalMode        = "vertical"
+	markdownMode        = "markdown"
+	verticalRowTemplate = "*************************** %d. row ***************************\n"
+	nullValue           = "NULL"
+	moreRowsTemplate    = "... %d more rows, use .maxrows or --max-rows to show more\n"
+	manyRowsTemplate    = "... more than %d more rows, use .maxrows or --max-rows to show more\n"
+	moreRowsLimit       = 10000
+	tableBlockRows      = 1000
+	minColumnWidth      = 4
+	ellipsis            = "…"
+)
+
+var outputModes = []string{tableMode, csvMode, tsvMode, jsonMode, jsonlMode, verticalMode, markdownMode}
+
+// isOutputMode check if mode is supported
+func isOutputMode(mode string) bool {
+	for _, m := range outputModes {
+		if m == mode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// printTable print rows as aligned table, truncating columns to fit the width when it is known, without row limit
+// rows are printed in blocks as they are read, aligned to the widest values read so far
+func (c *csvql) printTable(w io.Writer, columns []string, rows storage.Rows, width int, maxRows int) error {
+	var widths []int
+	printed := false
+	records := make([][]string, 0)
+	flush := func() error {
+		widths = fitWidths(growWidths(widths, columnWidths(columns, records)), width-1, table.DefaultPadding)
+		if err := c.printTableBlock(w, columns, records, widths, !printed); err != nil {
+			return err
+		}
+
+		printed = true
+		records = records[:0]
+
+		return nil
+	}
+
+	more, err := c.limitRows(rows, maxRows, func(values []interface{}) error {
+		record := make([]string, len(values))
+		for i, value := range values {
+			record[i] = formatValue(value)
+		}
+
+		if records = append(records, record); maxRows > 0 || len(records) < tableBlockRows {
+			return nil
+		}
+
+		return flush()
+	})
+	if err != nil {
+		return err
+	}
+
+	if !printed || len(records) > 0 {
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+
+	return printMoreRows(w, more)
+}
+
+// printTableBlock print records with the columns padded to widths, the header is printed only with the first block
+func (c *csvql) printTableBlock(w io.Writer, columns []string, records [][]string, widths []int, header bool) error {
+	cols := make([]interface{}, 0)
+	for i, c := range columns {
+		value := truncate(c, widths[i])
+		cols = append(cols, value+strings.Repeat(" ", widths[i]-utf8.RuneCountInString(value)))
+	}
+
+	var block bytes.Buffer
+	tbl := table.New(cols...).
+		WithFirstColumnFormatter(color.New(color.FgYellow).SprintfFunc()).
+		WithWriter(&block)
+
+	if header {
+		tbl.WithHeaderFormatter(color.New(color.FgGreen, color.Underline).SprintfFunc())
+	}
+
+	for _, record := range records {
+		values := make([]interface{}, len(record))
+		for i, value := range record {
+			values[i] = truncate(value, widths[i])
+		}
+
+		tbl.AddRow(values...)
+	}
+
+	_ = c.bar.Clear()
+	tbl.Print()
+
+	content := block.Bytes()
+	if !header {
+		content = content[bytes.IndexByte(content, '\n')+1:]
+	}
+
+	if _, err := w.Write(content); err != nil {
+		return fmt.Errorf("failed to print rows: %w", err)
+	}
+
+	return nil
+}
+
+// printVertical print each column of the row in its own line
+func (c *csvql) printVertical(w io.Writer, columns []string, rows storage.Rows, maxRows int) error {
+	width := 0
+	for _, col := range columns {
+		if n := utf8.RuneCountInString(col); n > width {
+			width = n
+		}
+	}
+
+	_ = c.bar.Clear()
+	n := 0
+	more, err := c.limitRows(rows, maxRows, func(values []interface{}) error {
+		n++
+
+		var raw strings.Builder
+		raw.WriteString(fmt.Sprintf(verticalRowTemplate, n))
+		for i, col := range columns {
+			raw.WriteString(fmt.Sprintf("%*s: %s\n", width, col, formatValue(values[i])))
+		}
+
+		if _, err := io.WriteString(w, raw.String()); err != nil {
+			return fmt.Errorf("failed to print row: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	return printMoreRows(w, more)
+}
+
+// limitRows read up to maxRows rows, unlimited when zero, and count the remaining ones up to moreRowsLimit,
+// more is moreRowsLimit+1 when there are even more rows
+func (c *csvql) limitRows(rows storage.Rows, maxRows int, fn func(values []interface{}) error) (int64, error) {
+	var read, more int64
+	for rows.Next() {
+		if maxRows > 0 && read >= int64(maxRows) {
+			if more++; more > moreRowsLimit {
+				return more, nil
+			}
+
+			continue
+		}
+
+		values, err := rows.Values()
+		if err != nil {
+			return 0, err
+		}
+
+		if err := fn(values); err != nil {
+			return 0, err
+		}
+		read++
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	return more, nil
+}
+
+// printMoreRows print footer with the number of rows not displayed
+func printMoreRows(w io.Writer, more int64) error {
+	if more == 0 {
+		return nil
+	}
+
+	footer := fmt.Sprintf(moreRowsTemplate, more)
+	if more > moreRowsLimit {
+		footer = fmt.Sprintf(manyRowsTemplate, moreRowsLimit)
+	}
+
+	if _, err := io.WriteString(w, footer); err != nil {
+		return fmt.Errorf("failed to print footer: %w", err)
+	}
+
+	return nil
+}
+
+// growWidths widest of the widths of each column
+func growWidths(widths []int, grown []int) []int {
+	if widths == nil {
+		return grown
+	}
+
+	for i := range grown {
+		if widths[i] > grown[i] {
+			grown[i] = widths[i]
+		}
+	}
+
+	return grown
+}
+
+// columnWidths width of each column, considering header and values
+func columnWidths(columns []string, records [][]string) []int {
+	widths := make([]int, len(columns))
+	for i, col := range columns {
+		widths[i] = utf8.RuneCountInString(col)
+	}
+
+	for _, record := range records {
+		for i, value := range record {
+			if n := utf8.RuneCountInString(value); n > widths[i] {
+				widths[i] = n
+			}
+		}
+	}
+
+	return widths
+}
+
+// fitWidths shrink the widest columns until the row, including padding, fits the limit, unlimited when not positive
+func fitWidths(widths []int, limit int, padding int) []int {
+	fitted := append([]int(nil), widths...)
+	if limit <= 0 {
+		return fitted
+	}
+
+	total := 0
+	for _, w := range fitted {
+		total += w + padding
+	}
+
+	for total > limit && len(fitted) > 0 {
+		widest := 0
+		for i := range fitted {
+			if fitted[i] > fitted[widest] {
+				widest = i
+			}
+		}
+
+		second := minColumnWidth
+		for i := range fitted {
+			if i != widest && fitted[i] > second {
+				second = fitted[i]
+			}
+		}
+
+		target := fitted[widest] - (total - limit)
+		if target < second {
+			target = second
+		}
+
+		if target >= fitted[widest] {
+			target = fitted[widest] - 1
+		}
+
+		if target < minColumnWidth {
+			break
+		}
+
+		total -= fitted[widest] - target
+		fitted[widest] = target
+	}
+
+	return fitted
+}
+
+// truncate cut value to width, marking it with an ellipsis
+func truncate(value string, width int) string {
+	if utf8.RuneCountInString(value) <= width {
+		return value
+	}
+
+	runes := []rune(value)
+	if width <= 1 {
+		return string(runes[:width])
+	}
+
+	return string(runes[:width-1]) + ellipsis
+}
+
+// printEncoded print up to maxRows rows using the encoder of an export type
+func (c *csvql) printEncoded(w io.Writer, exportType string, columns []string, rows storage.Rows, maxRows int) error {
+	newEncoder, err := exportdata.NewEncoderFactory(exportType, exportdata.Options{Pretty: true})
+	if err != nil {
+		return fmt.Errorf("failed to initialize output: %w", err)
+	}
+
+	_ = c.bar.Clear()
+	encoder := newEncoder(w)
+	if err := encoder.WriteHeader(columns); err != nil {
+		return fmt.Errorf("failed to print header: %w", err)
+	}
+
+	more, err := c.limitRows(rows, maxRows, func(values []interface{}) error {
+		if err := encoder.WriteRow(values); err != nil {
+			return fmt.Errorf("failed to print row: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := encoder.Flush(); err != nil {
+		return fmt.Errorf("failed to print rows: %w", err)
+	}
+
+	return printMoreRows(w, more)
+}
+
+// formatValue format value to be displayed
+func formatValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return nullValue
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
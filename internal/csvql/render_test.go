@@ -0,0 +1,185 @@
+package csvql
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/storage"
+	"bytes"
+	"github.com/schollz/progressbar/v3"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestShouldFitColumnWidthsWithSuccess(t *testing.T) {
+	tests := []struct {
+		widths  []int
+		limit   int
+		padding int
+		expects []int
+	}{
+		{
+			widths:  []int{10, 20, 30},
+			limit:   0,
+			padding: 2,
+			expects: []int{10, 20, 30},
+		},
+		{
+			widths:  []int{10, 20, 30},
+			limit:   80,
+			padding: 2,
+			expects: []int{10, 20, 30},
+		},
+		{
+			widths:  []int{10, 20, 300},
+			limit:   80,
+			padding: 2,
+			expects: []int{10, 20, 44},
+		},
+		{
+			widths:  []int{10, 100, 100},
+			limit:   56,
+			padding: 2,
+			expects: []int{10, 20, 20},
+		},
+		{
+			widths:  []int{6, 6, 6},
+			limit:   10,
+			padding: 2,
+			expects: []int{4, 4, 4},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expects, fitWidths(test.widths, test.limit, test.padding))
+	}
+}
+
+func TestShouldTruncateValuesWithSuccess(t *testing.T) {
+	assert.Equal(t, "value", truncate("value", 5))
+	assert.Equal(t, "val…", truncate("value", 4))
+	assert.Equal(t, "São…", truncate("São Paulo", 4))
+	assert.Equal(t, "v", truncate("value", 1))
+}
+
+func TestShouldPageContentWithSuccess(t *testing.T) {
+	content := []byte("1\n2\n3\n4\n5\n")
+
+	var out bytes.Buffer
+	assert.NoError(t, pageContent(&out, strings.NewReader("\n\n"), content, 2))
+	assert.Equal(t, 2, strings.Count(out.String(), pagerPrompt))
+	assert.True(t, strings.HasSuffix(out.String(), "5\n"))
+
+	out.Reset()
+	assert.NoError(t, pageContent(&out, strings.NewReader("q\n"), content, 2))
+	assert.Equal(t, 1, strings.Count(out.String(), pagerPrompt))
+	assert.NotContains(t, out.String(), "3\n")
+}
+
+func TestShouldRenderRowsWithSuccess(t *testing.T) {
+	tests := []struct {
+		mode    string
+		maxRows int
+		expects string
+	}{
+		{
+			mode:    csvMode,
+			expects: "id,name\n1,name_1\n2,\n3,name_3\n",
+		},
+		{
+			mode:    csvMode,
+			maxRows: 2,
+			expects: "id,name\n1,name_1\n2,\n... 1 more rows, use .maxrows or --max-rows to show more\n",
+		},
+		{
+			mode:    jsonlMode,
+			maxRows: 1,
+			expects: "{\"id\":1,\"name\":\"name_1\"}\n... 2 more rows, use .maxrows or --max-rows to show more\n",
+		},
+		{
+			mode:    verticalMode,
+			maxRows: 1,
+			expects: "*************************** 1. row ***************************\n  id: 1\nname: name_1\n" +
+				"... 2 more rows, use .maxrows or --max-rows to show more\n",
+		},
+	}
+
+	c := &csvql{bar: progressbar.NewOptions(0, progressbar.OptionSetWriter(io.Discard))}
+	for _, test := range tests {
+		rows := storage.NewRows([]storage.Column{{Name: "id"}, {Name: "name"}}, [][]any{
+			{int64(1), "name_1"},
+			{int64(2), nil},
+			{int64(3), "name_3"},
+		})
+
+		var out bytes.Buffer
+		assert.NoError(t, c.render(&out, test.mode, storage.ColumnNames(rows.Columns()), rows, 0, test.maxRows))
+		assert.Equal(t, test.expects, out.String(), test.mode)
+	}
+}
+
+func TestShouldAlignVerticalColumnsWithSuccess(t *testing.T) {
+	c := &csvql{bar: progressbar.NewOptions(0, progressbar.OptionSetWriter(io.Discard))}
+	rows := storage.NewRows([]storage.Column{{Name: "país"}, {Name: "id"}}, [][]any{{"Brasil", int64(1)}})
+
+	var out bytes.Buffer
+	assert.NoError(t, c.render(&out, verticalMode, storage.ColumnNames(rows.Columns()), rows, 0, 0))
+	assert.Contains(t, out.String(), "país: Brasil\n  id: 1\n")
+}
+
+func TestShouldStopCountingMoreRowsWithSuccess(t *testing.T) {
+	records := make([][]any, moreRowsLimit+10)
+	for i := range records {
+		records[i] = []any{int64(i)}
+	}
+
+	c := &csvql{bar: progressbar.NewOptions(0, progressbar.OptionSetWriter(io.Discard))}
+	rows := storage.NewRows([]storage.Column{{Name: "id"}}, records)
+
+	var out bytes.Buffer
+	assert.NoError(t, c.render(&out, csvMode, []string{"id"}, rows, 0, 1))
+	assert.Equal(t, "id\n0\n... more than 10000 more rows, use .maxrows or --max-rows to show more\n", out.String())
+	assert.True(t, rows.Next(), "remaining rows must not be read")
+}
+
+func TestShouldStreamTableWithoutRowLimitWithSuccess(t *testing.T) {
+	records := make([][]any, tableBlockRows+1)
+	for i := range records {
+		records[i] = []any{int64(1), "a"}
+	}
+	records[tableBlockRows] = []any{int64(1), "longer_value"}
+
+	c := &csvql{bar: progressbar.NewOptions(0, progressbar.OptionSetWriter(io.Discard))}
+	rows := storage.NewRows([]storage.Column{{Name: "id"}, {Name: "name"}}, records)
+
+	var out bytes.Buffer
+	assert.NoError(t, c.render(&out, tableMode, []string{"id", "name"}, rows, 0, 0))
+
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	assert.Len(t, lines, tableBlockRows+2)
+	assert.Equal(t, "id  name  ", lines[0])
+	assert.Equal(t, "1   a     ", lines[1])
+	assert.Equal(t, "1   longer_value  ", lines[len(lines)-1])
+	assert.Equal(t, 1, strings.Count(out.String(), "name"))
+}
+
+func TestShouldStreamPagesWithSuccess(t *testing.T) {
+	var out bytes.Buffer
+	p := &pager{out: &out, input: strings.NewReader("\n\n"), height: 3}
+	_, err := p.Write([]byte("1\n2\n"))
+	assert.NoError(t, err)
+	assert.Empty(t, out.String(), "content is held while it fits in the terminal")
+
+	_, err = p.Write([]byte("3\n4\n5\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, p.Close())
+	assert.Equal(t, 2, strings.Count(out.String(), pagerPrompt))
+	assert.True(t, strings.HasPrefix(out.String(), "1\n2\n"+pagerPrompt))
+	assert.True(t, strings.HasSuffix(out.String(), "5\n"))
+
+	out.Reset()
+	p = &pager{out: &out, input: strings.NewReader(""), height: 3}
+	_, err = p.Write([]byte("1\n2\n"))
+	assert.NoError(t, err)
+	assert.NoError(t, p.Close())
+	assert.Equal(t, "1\n2\n", out.String())
+}
@@ -0,0 +1,137 @@
+package csvql
+
+import (
+	"strings"
+	"unicode"
+)
+
+const verticalTerminator = `\G`
+
+// splitStatements split script in statements terminated by `;` or `\G`, ignoring terminators inside quotes and
+// comments, statements terminated by `\G` keep the terminator and the trailing content without terminator is
+// returned apart as remainder
+func splitStatements(script string) ([]string, string) {
+	statements := make([]string, 0)
+	runes := []rune(script)
+	start := 0
+	hasContent := false
+	var quote rune
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+			hasContent = true
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				i++
+			}
+			i++
+		case r == '\\' && i+1 < len(runes) && runes[i+1] == 'G':
+			if hasContent {
+				statements = append(statements, strings.TrimSpace(string(runes[start:i]))+verticalTerminator)
+			}
+			i++
+			start = i + 1
+			hasContent = false
+		case r == ';':
+			if hasContent {
+				statements = append(statements, strings.TrimSpace(string(runes[start:i])))
+			}
+			start = i + 1
+			hasContent = false
+		case !isSpace(r):
+			hasContent = true
+		}
+	}
+
+	if !hasContent || start >= len(runes) {
+		return statements, ""
+	}
+
+	return statements, strings.TrimSpace(string(runes[start:]))
+}
+
+// scriptStatements split script in statements, including the trailing statement without terminator
+func scriptStatements(script string) []string {
+	statements, remainder := splitStatements(script)
+	if remainder != "" {
+		statements = append(statements, remainder)
+	}
+
+	return statements
+}
+
+// returnsRows check if the statement returns rows by its first keyword, skipping comments
+func returnsRows(statement string) bool {
+	switch strings.ToLower(firstKeyword(statement)) {
+	case "select", "with", "values", "pragma", "explain", "describe", "show", "summarize", "from":
+		return true
+	}
+
+	return false
+}
+
+// changesRows check if the statement is a DML changing rows, so the number of affected rows is meaningful
+func changesRows(statement string) bool {
+	switch strings.ToLower(firstKeyword(statement)) {
+	case "insert", "update", "delete", "replace":
+		return true
+	}
+
+	return false
+}
+
+// firstKeyword first word of the statement, skipping whitespaces, comments and parentheses
+func firstKeyword(statement string) string {
+	runes := []rune(statement)
+	for i := 0; i < len(runes); i++ {
+		switch r := runes[i]; {
+		case isSpace(r) || r == '(':
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				i++
+			}
+			i++
+		default:
+			end := i
+			for end < len(runes) && unicode.IsLetter(runes[end]) {
+				end++
+			}
+
+			return string(runes[i:end])
+		}
+	}
+
+	return ""
+}
+
+// cutVerticalTerminator remove the `\G` terminator, reporting if the statement had it
+func cutVerticalTerminator(statement string) (string, bool) {
+	statement = strings.TrimSpace(statement)
+	if strings.HasSuffix(statement, verticalTerminator) {
+		return strings.TrimSpace(strings.TrimSuffix(statement, verticalTerminator)), true
+	}
+
+	return statement, false
+}
+
+// isSpace check if rune is a whitespace
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\n' || r == '\r'
+}
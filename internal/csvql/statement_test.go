@@ -0,0 +1,74 @@
+package csvql
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestShouldSplitStatementsWithSuccess(t *testing.T) {
+	tests := []struct {
+		script     string
+		statements []string
+		remainder  string
+	}{
+		{
+			script:     "select 1; select 2;",
+			statements: []string{"select 1", "select 2"},
+		},
+		{
+			script:     "select ';' from rows;\nselect \"a;b\", `c;d`, ['e;f'] from rows",
+			statements: []string{"select ';' from rows"},
+			remainder:  "select \"a;b\", `c;d`, ['e;f'] from rows",
+		},
+		{
+			script:     "select [1, 2][1]; select list_value(3)[1];",
+			statements: []string{"select [1, 2][1]", "select list_value(3)[1]"},
+		},
+		{
+			script:     "-- comment; with terminator\nselect 1; /* block; comment */ select 2;",
+			statements: []string{"-- comment; with terminator\nselect 1", "/* block; comment */ select 2"},
+		},
+		{
+			script:     ";; select 1 ; -- trailing comment",
+			statements: []string{"select 1"},
+		},
+		{
+			script:     "select * from rows\\G select '\\G' from rows;",
+			statements: []string{"select * from rows\\G", "select '\\G' from rows"},
+		},
+		{
+			script:     "select 'unterminated;",
+			statements: []string{},
+			remainder:  "select 'unterminated;",
+		},
+	}
+
+	for _, test := range tests {
+		statements, remainder := splitStatements(test.script)
+		assert.Equal(t, test.statements, statements)
+		assert.Equal(t, test.remainder, remainder)
+	}
+}
+
+func TestShouldClassifyStatementsWithSuccess(t *testing.T) {
+	tests := []struct {
+		statement   string
+		returnsRows bool
+		changesRows bool
+	}{
+		{statement: "select 1", returnsRows: true},
+		{statement: "-- comment\n/* block */ (select 1)", returnsRows: true},
+		{statement: "WITH t AS (select 1) select * from t", returnsRows: true},
+		{statement: "pragma table_info('rows')", returnsRows: true},
+		{statement: "describe rows", returnsRows: true},
+		{statement: "insert into rows values (1)", changesRows: true},
+		{statement: "/* comment */ DELETE from rows", changesRows: true},
+		{statement: "create table t (a int)"},
+		{statement: ""},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.returnsRows, returnsRows(test.statement), test.statement)
+		assert.Equal(t, test.changesRows, changesRows(test.statement), test.statement)
+	}
+}
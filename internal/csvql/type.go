@@ -1,11 +1,50 @@
 package csvql
 
+import (
+	"adrianolaselva.github.io/csvql/pkg/datasize"
+	"time"
+)
+
 type Params struct {
-	FileInputs     []string
-	DataSourceName string
-	Delimiter      string
-	Query          string
-	Export         string
-	Type           string
-	Lines          int
+	FileInputs      []string
+	DataSourceName  string
+	Delimiter       string
+	Query           string
+	Export          string
+	Type            string
+	Lines           int
+	GroupBy         []string
+	Pretty          bool
+	SplitRows       int
+	SplitBytes      datasize.Size
+	PartitionBy     []string
+	MaxOpenFiles    int
+	Template        string
+	Overwrite       bool
+	Append          bool
+	NoClobber       bool
+	Count           bool
+	Timeout         time.Duration
+	MaxRows         int
+	NoPager         bool
+	Output          string
+	Quiet           bool
+	QueryFile       string
+	ContinueOnError bool
+	Variables       []string
+	Refresh         bool
+	IfExists        string
+	PrimaryKey      []string
+	ImportMode      string
+	Indexes         []string
+	AutoIndex       bool
+	Lazy            bool
+	LazyStats       bool
+	Select          []string
+	Where           string
+	Sample          string
+	SampleBy        string
+	Seed            int64
+	MemoryLimit     datasize.Size
+	Engine          string
 }
@@ -3,23 +3,84 @@ package exportdata
 import (
 	"adrianolaselva.github.io/csvql/pkg/exportdata"
 	"adrianolaselva.github.io/csvql/pkg/exportdata/csv"
+	"adrianolaselva.github.io/csvql/pkg/exportdata/json"
 	"adrianolaselva.github.io/csvql/pkg/exportdata/jsonl"
-	"database/sql"
+	"adrianolaselva.github.io/csvql/pkg/exportdata/markdown"
+	"adrianolaselva.github.io/csvql/pkg/exportdata/template"
+	"adrianolaselva.github.io/csvql/pkg/storage"
 	"fmt"
 	"github.com/schollz/progressbar/v3"
+	"io"
 )
 
 const (
 	CSVLineExportType  = "csv"
+	TSVLineExportType  = "tsv"
 	JSONLineExportType = "jsonl"
+	JSONExportType     = "json"
+	TemplateExportType = "template"
+	MarkdownExportType = "markdown"
 )
 
-func NewExport(exportType string, rows *sql.Rows, exportPath string, bar *progressbar.ProgressBar) (exportdata.Export, error) {
+// Options format and destination export settings
+type Options struct {
+	Pretty       bool
+	GroupBy      []string
+	SplitRows    int
+	SplitBytes   int64
+	PartitionBy  []string
+	MaxOpenFiles int
+	Template     string
+	Mode         exportdata.WriteMode
+}
+
+func NewExport(exportType string, rows storage.Rows, exportPath string, bar *progressbar.ProgressBar, opts Options) (exportdata.Export, error) {
+	newEncoder, err := NewEncoderFactory(exportType, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(opts.GroupBy) > 0 && (opts.SplitRows > 0 || opts.SplitBytes > 0) {
+		return nil, fmt.Errorf("split is not supported with group by")
+	}
+
+	return exportdata.NewRowsExport(rows, exportPath, bar, newEncoder, exportdata.Options{
+		SplitRows:    opts.SplitRows,
+		SplitBytes:   opts.SplitBytes,
+		PartitionBy:  opts.PartitionBy,
+		MaxOpenFiles: opts.MaxOpenFiles,
+		Mode:         opts.Mode,
+	}), nil
+}
+
+// NewEncoderFactory build the encoder factory of the export type
+func NewEncoderFactory(exportType string, opts Options) (exportdata.EncoderFactory, error) {
 	switch exportType {
 	case CSVLineExportType:
-		return csv.NewCsvExport(rows, exportPath, bar), nil
+		return csv.NewCsvEncoder, nil
+	case TSVLineExportType:
+		return csv.NewTsvEncoder, nil
 	case JSONLineExportType:
-		return jsonl.NewJsonlExport(rows, exportPath, bar), nil
+		return jsonl.NewJsonlEncoder, nil
+	case MarkdownExportType:
+		return markdown.NewMarkdownEncoder, nil
+	case JSONExportType:
+		return func(w io.Writer) exportdata.Encoder {
+			return json.NewJsonEncoder(w, opts.Pretty, opts.GroupBy)
+		}, nil
+	case TemplateExportType:
+		if opts.Template == "" {
+			return nil, fmt.Errorf("template file is required for %s export", exportType)
+		}
+
+		tmpl, err := template.Parse(opts.Template)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load template: %w", err)
+		}
+
+		return func(w io.Writer) exportdata.Encoder {
+			return template.NewTemplateEncoder(w, tmpl)
+		}, nil
 	}
 
 	return nil, fmt.Errorf("export type %s not defined", exportType)
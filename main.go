@@ -2,13 +2,21 @@ package main
 
 import (
 	"adrianolaselva.github.io/csvql/cmd"
+	"adrianolaselva.github.io/csvql/internal/csvql"
+	"errors"
 	"fmt"
 	"os"
 )
 
+const interruptExitCode = 130
+
 func main() {
 	if err := cmd.New().Execute(); err != nil {
 		fmt.Fprintf(os.Stderr, "%s\n", err.Error())
+		if errors.Is(err, csvql.ErrInterrupted) {
+			os.Exit(interruptExitCode)
+		}
+
 		os.Exit(1)
 	}
 }
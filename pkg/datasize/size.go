@@ -0,0 +1,85 @@
+package datasize
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var sizeRegex = regexp.MustCompile(`^(\d+(?:\.\d+)?)\s*([a-zA-Z]*)$`)
+
+var units = map[string]float64{
+	"":    1,
+	"b":   1,
+	"k":   1000,
+	"kb":  1000,
+	"m":   1000 * 1000,
+	"mb":  1000 * 1000,
+	"g":   1000 * 1000 * 1000,
+	"gb":  1000 * 1000 * 1000,
+	"kib": 1024,
+	"mib": 1024 * 1024,
+	"gib": 1024 * 1024 * 1024,
+}
+
+// Size amount of bytes usable as command flag, accepting values like `512`, `100MB` or `1GiB`
+type Size int64
+
+// Parse convert human readable size to bytes
+func Parse(value string) (Size, error) {
+	matches := sizeRegex.FindStringSubmatch(strings.TrimSpace(value))
+	if matches == nil {
+		return 0, fmt.Errorf("invalid size %s", value)
+	}
+
+	multiplier, ok := units[strings.ToLower(matches[2])]
+	if !ok {
+		return 0, fmt.Errorf("invalid size unit %s", matches[2])
+	}
+
+	amount, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size %s: %w", value, err)
+	}
+
+	return Size(amount * multiplier), nil
+}
+
+// String format size in bytes
+func (s *Size) String() string {
+	return strconv.FormatInt(int64(*s), 10)
+}
+
+// Set parse flag value
+func (s *Size) Set(value string) error {
+	size, err := Parse(value)
+	if err != nil {
+		return err
+	}
+
+	*s = size
+
+	return nil
+}
+
+// Type flag type name
+func (s *Size) Type() string {
+	return "size"
+}
+
+// Format format bytes as human readable size
+func Format(bytes int64) string {
+	const unit = 1000
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %cB", float64(bytes)/float64(div), "kMGTPE"[exp])
+}
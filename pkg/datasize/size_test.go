@@ -0,0 +1,39 @@
+package datasize_test
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/datasize"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestShouldParseSizeWithSuccess(t *testing.T) {
+	tests := []struct {
+		value   string
+		expects datasize.Size
+	}{
+		{value: "512", expects: 512},
+		{value: "10kb", expects: 10 * 1000},
+		{value: "100MB", expects: 100 * 1000 * 1000},
+		{value: "1.5G", expects: 1500 * 1000 * 1000},
+		{value: "2MiB", expects: 2 * 1024 * 1024},
+	}
+
+	for _, test := range tests {
+		size, err := datasize.Parse(test.value)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expects, size)
+	}
+}
+
+func TestShouldFailToParseInvalidSize(t *testing.T) {
+	for _, value := range []string{"", "MB", "10XB", "-1"} {
+		_, err := datasize.Parse(value)
+		assert.Error(t, err)
+	}
+}
+
+func TestShouldFormatSizeWithSuccess(t *testing.T) {
+	assert.Equal(t, "512 B", datasize.Format(512))
+	assert.Equal(t, "1.5 kB", datasize.Format(1500))
+	assert.Equal(t, "100.0 MB", datasize.Format(100*1000*1000))
+}
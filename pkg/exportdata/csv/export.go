@@ -2,126 +2,100 @@ package csv
 
 import (
 	"adrianolaselva.github.io/csvql/pkg/exportdata"
-	"database/sql"
 	"encoding/csv"
 	"fmt"
-	"github.com/schollz/progressbar/v3"
-	"os"
-	"path/filepath"
+	"io"
+	"strings"
 )
 
-const (
-	fileModeDefault os.FileMode = 0644
-)
-
-type csvExport struct {
-	rows       *sql.Rows
-	bar        *progressbar.ProgressBar
-	file       *os.File
-	exportPath string
-	columns    []string
+type csvEncoder struct {
+	writer *csv.Writer
 }
 
-func NewCsvExport(rows *sql.Rows, exportPath string, bar *progressbar.ProgressBar) exportdata.Export {
-	return &csvExport{rows: rows, exportPath: exportPath, bar: bar}
+func NewCsvEncoder(w io.Writer) exportdata.Encoder {
+	return &csvEncoder{writer: csv.NewWriter(w)}
 }
 
-// Export rows in file
-func (c *csvExport) Export() error {
-	if err := c.loadColumns(); err != nil {
-		return fmt.Errorf("failed to load columns: %w", err)
-	}
-
-	if err := c.openFile(); err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
+// NewTsvEncoder encoder writing tab separated values
+func NewTsvEncoder(w io.Writer) exportdata.Encoder {
+	writer := csv.NewWriter(w)
+	writer.Comma = '\t'
 
-	w := csv.NewWriter(c.file)
-	defer w.Flush()
+	return &csvEncoder{writer: writer}
+}
 
-	if err := w.Write(c.columns); err != nil {
+// WriteHeader write columns as first line
+func (c *csvEncoder) WriteHeader(columns []string) error {
+	if err := c.writer.Write(columns); err != nil {
 		return fmt.Errorf("failed to write headers: %w", err)
 	}
 
-	for c.rows.Next() {
-		_ = c.bar.Add(1)
-		if err := c.readAndAppendFile(w); err != nil {
-			return fmt.Errorf("failed to read and append line in file: %w", err)
-		}
-	}
-
-	return nil
+	return c.Flush()
 }
 
-// readAndAppendFile read line and append in file
-func (c *csvExport) readAndAppendFile(w *csv.Writer) error {
-	values := make([]interface{}, len(c.columns))
-	pointers := make([]interface{}, len(c.columns))
-	for i := range values {
-		pointers[i] = &values[i]
-	}
-
-	if err := c.rows.Scan(pointers...); err != nil {
-		return fmt.Errorf("failed to load row: %w", err)
+// AppendHeader check that the header of the existing file matches the columns
+func (c *csvEncoder) AppendHeader(existing io.Reader, columns []string) error {
+	header, err := csv.NewReader(existing).Read()
+	if err != nil {
+		return fmt.Errorf("failed to read existing headers: %w", err)
 	}
 
-	if err := w.Write(c.convertToStringArray(values)); err != nil {
-		return fmt.Errorf("failed to write row: %w", err)
+	if !c.equalColumns(header, columns) {
+		return fmt.Errorf("existing headers [%s] do not match columns [%s]", strings.Join(header, ","), strings.Join(columns, ","))
 	}
 
 	return nil
 }
 
-// convertToStringArray convert string array to string array
-func (c *csvExport) convertToStringArray(records []interface{}) []string {
-	values := make([]string, 0, len(records))
-	for _, r := range records {
-		values = append(values, r.(string))
+// WriteRow write row as csv line
+func (c *csvEncoder) WriteRow(values []any) error {
+	if err := c.writer.Write(c.convertToStringArray(values)); err != nil {
+		return fmt.Errorf("failed to write row: %w", err)
 	}
 
-	return values
+	return c.Flush()
 }
 
-// Close execute in defer
-func (c *csvExport) Close() error {
-	defer func(file *os.File) {
-		_ = file.Close()
-	}(c.file)
+// Flush write buffered lines
+func (c *csvEncoder) Flush() error {
+	c.writer.Flush()
+	if err := c.writer.Error(); err != nil {
+		return fmt.Errorf("failed to flush rows: %w", err)
+	}
 
 	return nil
 }
 
-// openFile open file
-func (c *csvExport) openFile() error {
-	if _, err := os.Stat(c.exportPath); !os.IsNotExist(err) {
-		err := os.Remove(c.exportPath)
-		if err != nil {
-			return fmt.Errorf("failed to remove file: %w", err)
-		}
-	}
-
-	if err := os.MkdirAll(filepath.Dir(c.exportPath), os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create path: %w", err)
+// equalColumns compare header and columns
+func (c *csvEncoder) equalColumns(header []string, columns []string) bool {
+	if len(header) != len(columns) {
+		return false
 	}
 
-	file, err := os.OpenFile(c.exportPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, fileModeDefault)
-	if err != nil {
-		return fmt.Errorf("failed to open file %s: %w", c.exportPath, err)
+	for i := range header {
+		if header[i] != columns[i] {
+			return false
+		}
 	}
 
-	c.file = file
-
-	return nil
+	return true
 }
 
-// loadColumns load columns
-func (c *csvExport) loadColumns() error {
-	columns, err := c.rows.Columns()
-	if err != nil {
-		return fmt.Errorf("failed to load columns: %w", err)
+// convertToStringArray convert any array to string array
+func (c *csvEncoder) convertToStringArray(records []any) []string {
+	values := make([]string, 0, len(records))
+	for _, r := range records {
+		switch v := r.(type) {
+		case nil:
+			values = append(values, "")
+		case string:
+			values = append(values, v)
+		case []byte:
+			values = append(values, string(v))
+		default:
+			values = append(values, fmt.Sprintf("%v", v))
+		}
 	}
 
-	c.columns = columns
-
-	return nil
+	return values
 }
@@ -0,0 +1,462 @@
+package exportdata
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/storage"
+	"bytes"
+	"container/list"
+	"fmt"
+	"github.com/schollz/progressbar/v3"
+	"io"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	maxOpenFilesDefault = 32
+	partitionDefault    = "__HIVE_DEFAULT_PARTITION__"
+	partitionEscapeSet  = "\"#%'*/:=?\\\x7f{}[]^<>|"
+)
+
+// Options export destination settings
+type Options struct {
+	SplitRows    int
+	SplitBytes   int64
+	PartitionBy  []string
+	MaxOpenFiles int
+	Mode         WriteMode
+}
+
+// target output of a partition, the whole result is a single target when no partition is used
+type target struct {
+	basePath string
+	output   Output
+	encoder  Encoder
+	current  string
+	parts    int
+	rows     int
+	bytes    int64
+	element  *list.Element
+}
+
+type rowsExport struct {
+	rows             storage.Rows
+	bar              *progressbar.ProgressBar
+	newEncoder       EncoderFactory
+	opts             Options
+	exportPath       string
+	columns          []string
+	dataColumns      []string
+	dataIndexes      []int
+	partitionIndexes []int
+	files            []string
+	total            int64
+	written          int64
+	outputs          []Output
+	committed        bool
+	targets          map[string]*target
+	order            []*target
+	open             *list.List
+	stage            bytes.Buffer
+}
+
+func NewRowsExport(rows storage.Rows, exportPath string, bar *progressbar.ProgressBar, newEncoder EncoderFactory, opts Options) Export {
+	if opts.MaxOpenFiles <= 0 {
+		opts.MaxOpenFiles = maxOpenFilesDefault
+	}
+
+	return &rowsExport{
+		rows:       rows,
+		exportPath: exportPath,
+		bar:        bar,
+		newEncoder: newEncoder,
+		opts:       opts,
+		targets:    map[string]*target{},
+		open:       list.New(),
+	}
+}
+
+// Export rows in file, rotating files when split limits are reached and routing rows to their partitions
+func (e *rowsExport) Export() error {
+	if (e.isSplit() || e.isPartitioned()) && IsStdout(e.exportPath) {
+		return fmt.Errorf("split and partition are not supported when exporting to stdout")
+	}
+
+	if err := e.loadColumns(); err != nil {
+		return fmt.Errorf("failed to load columns: %w", err)
+	}
+
+	for e.rows.Next() {
+		_ = e.bar.Add(1)
+		values, err := e.readRow()
+		if err != nil {
+			return fmt.Errorf("failed to read line: %w", err)
+		}
+
+		if err := e.writeRow(e.resolveTarget(values), e.dataValues(values)); err != nil {
+			return fmt.Errorf("failed to read and append line in file: %w", err)
+		}
+	}
+
+	if err := e.rows.Err(); err != nil {
+		return fmt.Errorf("failed to read rows: %w", err)
+	}
+
+	if len(e.order) == 0 && !e.isPartitioned() {
+		if err := e.rotate(e.resolveTarget(nil)); err != nil {
+			return err
+		}
+	}
+
+	for _, t := range e.order {
+		if err := e.finish(t); err != nil {
+			return err
+		}
+	}
+
+	return e.commit()
+}
+
+// Files return produced files
+func (e *rowsExport) Files() []string {
+	return e.files
+}
+
+// Rows return number of exported rows, safe to be called while exporting
+func (e *rowsExport) Rows() int64 {
+	return atomic.LoadInt64(&e.total)
+}
+
+// Bytes return number of written bytes before compression, safe to be called while exporting
+func (e *rowsExport) Bytes() int64 {
+	return atomic.LoadInt64(&e.written)
+}
+
+// Close execute in defer, discarding written files when the export did not finish
+func (e *rowsExport) Close() error {
+	if e.committed {
+		return nil
+	}
+
+	var abortErr error
+	for _, output := range e.outputs {
+		if err := output.Abort(); err != nil && abortErr == nil {
+			abortErr = err
+		}
+	}
+
+	e.outputs = nil
+
+	return abortErr
+}
+
+// commit move all written files to their export paths
+func (e *rowsExport) commit() error {
+	for len(e.outputs) > 0 {
+		if err := e.outputs[0].Commit(); err != nil {
+			return fmt.Errorf("failed to commit file: %w", err)
+		}
+
+		e.outputs = e.outputs[1:]
+	}
+
+	e.committed = true
+
+	return nil
+}
+
+// writeRow encode row in the current file of the target, when it exceeds the limits the row is encoded again in a new file
+func (e *rowsExport) writeRow(t *target, values []any) error {
+	if t.encoder == nil || (e.opts.SplitRows > 0 && t.rows >= e.opts.SplitRows) {
+		if err := e.rotate(t); err != nil {
+			return err
+		}
+	} else if err := e.acquire(t); err != nil {
+		return err
+	}
+
+	if err := t.encoder.WriteRow(values); err != nil {
+		return fmt.Errorf("failed to encode row: %w", err)
+	}
+
+	if e.opts.SplitBytes > 0 && t.rows > 0 && t.bytes+int64(e.stage.Len()) > e.opts.SplitBytes {
+		e.stage.Reset()
+		if err := e.rotate(t); err != nil {
+			return err
+		}
+
+		if err := t.encoder.WriteRow(values); err != nil {
+			return fmt.Errorf("failed to encode row: %w", err)
+		}
+	}
+
+	t.rows++
+	atomic.AddInt64(&e.total, 1)
+
+	return e.flushStage(t)
+}
+
+// rotate finish current file of the target and open the next one writing the header
+func (e *rowsExport) rotate(t *target) error {
+	if t.encoder != nil {
+		if err := e.finish(t); err != nil {
+			return err
+		}
+	}
+
+	t.parts++
+	t.current = t.basePath
+	if e.isSplit() {
+		t.current = SplitPath(t.basePath, t.parts)
+	}
+
+	output, err := OpenOutput(t.current, e.opts.Mode)
+	if err != nil {
+		return fmt.Errorf("failed to open file: %w", err)
+	}
+
+	e.outputs = append(e.outputs, output)
+	t.output = output
+	if err := e.register(t); err != nil {
+		return err
+	}
+
+	e.files = append(e.files, t.current)
+	t.rows = 0
+	t.bytes = 0
+	e.stage.Reset()
+	t.encoder = e.newEncoder(&e.stage)
+
+	if output.Appending() {
+		return e.appendHeader(t)
+	}
+
+	if err := t.encoder.WriteHeader(e.dataColumns); err != nil {
+		return fmt.Errorf("failed to write headers: %w", err)
+	}
+
+	return e.flushStage(t)
+}
+
+// appendHeader validate the content of the existing file instead of writing the header
+func (e *rowsExport) appendHeader(t *target) error {
+	appendable, ok := t.encoder.(Appendable)
+	if !ok {
+		return fmt.Errorf("export format does not support appending to the existing file %s", t.current)
+	}
+
+	existing, err := OpenReader(t.current)
+	if err != nil {
+		return fmt.Errorf("failed to read existing file: %w", err)
+	}
+	defer func(existing io.ReadCloser) {
+		_ = existing.Close()
+	}(existing)
+
+	if err := appendable.AppendHeader(existing, e.dataColumns); err != nil {
+		return fmt.Errorf("failed to append to file %s: %w", t.current, err)
+	}
+
+	return nil
+}
+
+// finish write pending content of the encoder and close the current file of the target
+func (e *rowsExport) finish(t *target) error {
+	if err := e.acquire(t); err != nil {
+		return err
+	}
+
+	e.stage.Reset()
+	if err := t.encoder.Flush(); err != nil {
+		return fmt.Errorf("failed to flush file: %w", err)
+	}
+
+	if err := e.flushStage(t); err != nil {
+		return err
+	}
+
+	return e.release(t)
+}
+
+// acquire make sure the current file of the target is open, reopening it when it was released
+func (e *rowsExport) acquire(t *target) error {
+	if t.element != nil {
+		e.open.MoveToBack(t.element)
+		return nil
+	}
+
+	if err := t.output.Reopen(); err != nil {
+		return fmt.Errorf("failed to reopen file: %w", err)
+	}
+
+	return e.register(t)
+}
+
+// register track open file, releasing the least recently used one when the limit is reached
+func (e *rowsExport) register(t *target) error {
+	for e.open.Len() >= e.opts.MaxOpenFiles {
+		if err := e.release(e.open.Front().Value.(*target)); err != nil {
+			return err
+		}
+	}
+
+	t.element = e.open.PushBack(t)
+
+	return nil
+}
+
+// release close file of the target keeping the encoder state, so it can be reopened later
+func (e *rowsExport) release(t *target) error {
+	if t.element == nil {
+		return nil
+	}
+
+	e.open.Remove(t.element)
+	t.element = nil
+
+	if err := t.output.Close(); err != nil {
+		return fmt.Errorf("failed to close file %s: %w", t.current, err)
+	}
+
+	return nil
+}
+
+// flushStage move encoded content to the current file of the target
+func (e *rowsExport) flushStage(t *target) error {
+	n, err := e.stage.WriteTo(t.output)
+	t.bytes += n
+	atomic.AddInt64(&e.written, n)
+	if err != nil {
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+
+	return nil
+}
+
+// resolveTarget find or create the target of the row based on the partition columns
+func (e *rowsExport) resolveTarget(values []any) *target {
+	segments := make([]string, 0, len(e.partitionIndexes))
+	for _, i := range e.partitionIndexes {
+		segments = append(segments, fmt.Sprintf("%s=%s", escapePartition(e.columns[i]), escapePartition(values[i])))
+	}
+
+	key := filepath.Join(segments...)
+	if t, ok := e.targets[key]; ok {
+		return t
+	}
+
+	t := &target{basePath: e.exportPath}
+	if e.isPartitioned() {
+		dir, name := filepath.Split(e.exportPath)
+		t.basePath = filepath.Join(dir, key, name)
+	}
+
+	e.targets[key] = t
+	e.order = append(e.order, t)
+
+	return t
+}
+
+// dataValues remove partition columns from row
+func (e *rowsExport) dataValues(values []any) []any {
+	if !e.isPartitioned() {
+		return values
+	}
+
+	data := make([]any, 0, len(e.dataIndexes))
+	for _, i := range e.dataIndexes {
+		data = append(data, values[i])
+	}
+
+	return data
+}
+
+// isSplit check if the result must be split in multiple files
+func (e *rowsExport) isSplit() bool {
+	return e.opts.SplitRows > 0 || e.opts.SplitBytes > 0
+}
+
+// isPartitioned check if the result must be partitioned by columns values
+func (e *rowsExport) isPartitioned() bool {
+	return len(e.opts.PartitionBy) > 0
+}
+
+// readRow read current row
+func (e *rowsExport) readRow() ([]any, error) {
+	values, err := e.rows.Values()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load row: %w", err)
+	}
+
+	return values, nil
+}
+
+// loadColumns load columns and split them between partition and data columns
+func (e *rowsExport) loadColumns() error {
+	columns := storage.ColumnNames(e.rows.Columns())
+	e.columns = columns
+	e.dataColumns = columns
+
+	if !e.isPartitioned() {
+		return nil
+	}
+
+	indexes := map[string]int{}
+	for i, c := range columns {
+		indexes[c] = i
+	}
+
+	partitioned := map[int]bool{}
+	for _, p := range e.opts.PartitionBy {
+		i, ok := indexes[p]
+		if !ok {
+			return fmt.Errorf("partition column %s not found in result", p)
+		}
+
+		e.partitionIndexes = append(e.partitionIndexes, i)
+		partitioned[i] = true
+	}
+
+	e.dataColumns = make([]string, 0, len(columns))
+	for i, c := range columns {
+		if !partitioned[i] {
+			e.dataColumns = append(e.dataColumns, c)
+			e.dataIndexes = append(e.dataIndexes, i)
+		}
+	}
+
+	if len(e.dataColumns) == 0 {
+		return fmt.Errorf("at least one column must remain after removing partition columns")
+	}
+
+	return nil
+}
+
+// escapePartition format value as a directory name, escaping characters not allowed in paths like hive does
+func escapePartition(value any) string {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+		return partitionDefault
+	case []byte:
+		raw = string(v)
+	default:
+		raw = fmt.Sprintf("%v", v)
+	}
+
+	if raw == "" {
+		return partitionDefault
+	}
+
+	var escaped strings.Builder
+	for _, b := range []byte(raw) {
+		if b < 0x20 || strings.IndexByte(partitionEscapeSet, b) >= 0 {
+			escaped.WriteString(fmt.Sprintf("%%%02X", b))
+			continue
+		}
+
+		escaped.WriteByte(b)
+	}
+
+	return escaped.String()
+}
@@ -0,0 +1,213 @@
+package exportdata_test
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/exportdata"
+	"adrianolaselva.github.io/csvql/pkg/exportdata/csv"
+	"adrianolaselva.github.io/csvql/pkg/storage"
+	"adrianolaselva.github.io/csvql/pkg/storage/sqlite"
+	"fmt"
+	"github.com/schollz/progressbar/v3"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShouldSplitExportWithSuccess(t *testing.T) {
+	tests := []struct {
+		total   int
+		opts    exportdata.Options
+		expects []string
+	}{
+		{
+			total: 3,
+			opts:  exportdata.Options{},
+			expects: []string{
+				"id\n1\n2\n3\n",
+			},
+		},
+		{
+			total: 5,
+			opts:  exportdata.Options{SplitRows: 2},
+			expects: []string{
+				"id\n1\n2\n",
+				"id\n3\n4\n",
+				"id\n5\n",
+			},
+		},
+		{
+			total: 4,
+			opts:  exportdata.Options{SplitBytes: 7},
+			expects: []string{
+				"id\n1\n2\n",
+				"id\n3\n4\n",
+			},
+		},
+		{
+			total: 0,
+			opts:  exportdata.Options{SplitRows: 2},
+			expects: []string{
+				"id\n",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		storage, err := sqlite.NewSqLiteStorage(":memory:")
+		assert.NoError(t, err)
+
+		assert.NoError(t, storage.BuildStructure("rows", []string{"id"}))
+		for i := 1; i <= test.total; i++ {
+			assert.NoError(t, storage.InsertRow("rows", []string{"id"}, []any{fmt.Sprintf("%d", i)}))
+		}
+
+		rows, err := storage.Query("select * from rows;")
+		assert.NoError(t, err)
+
+		exportPath := filepath.Join(t.TempDir(), "result.csv")
+		export := exportdata.NewRowsExport(rows, exportPath, progressbar.NewOptions(0, progressbar.OptionSetWriter(io.Discard)), csv.NewCsvEncoder, test.opts)
+		assert.NoError(t, export.Export())
+		assert.NoError(t, export.Close())
+		assert.Len(t, export.Files(), len(test.expects))
+
+		for i, file := range export.Files() {
+			payload, err := os.ReadFile(file)
+			assert.NoError(t, err)
+			assert.Equal(t, test.expects[i], string(payload))
+		}
+
+		assert.NoError(t, rows.Close())
+		assert.NoError(t, storage.Close())
+	}
+}
+
+func TestShouldBuildSplitPath(t *testing.T) {
+	assert.Equal(t, filepath.Join("out", "result-0001.csv"), exportdata.SplitPath(filepath.Join("out", "result.csv"), 1))
+	assert.Equal(t, "result-0012.csv.gz", exportdata.SplitPath("result.csv.gz", 12))
+	assert.Equal(t, "result-0002", exportdata.SplitPath("result", 2))
+}
+
+func TestShouldPartitionExportWithSuccess(t *testing.T) {
+	storage, err := sqlite.NewSqLiteStorage(":memory:")
+	assert.NoError(t, err)
+	defer func() {
+		_ = storage.Close()
+	}()
+
+	columns := []string{"year", "month", "id"}
+	assert.NoError(t, storage.BuildStructure("rows", columns))
+	for _, row := range [][]any{
+		{"2023", "01", "1"},
+		{"2023", "02", "2"},
+		{"2023", "01", "3"},
+		{"2022", "12", "4"},
+		{"2023", "02", "5"},
+		{"2023", "01", "6"},
+	} {
+		assert.NoError(t, storage.InsertRow("rows", columns, row))
+	}
+
+	rows, err := storage.Query("select * from rows;")
+	assert.NoError(t, err)
+	defer func() {
+		_ = rows.Close()
+	}()
+
+	root := t.TempDir()
+	exportPath := filepath.Join(root, "part-0.csv")
+	export := exportdata.NewRowsExport(rows, exportPath, progressbar.NewOptions(0, progressbar.OptionSetWriter(io.Discard)), csv.NewCsvEncoder, exportdata.Options{
+		PartitionBy:  []string{"year", "month"},
+		MaxOpenFiles: 1,
+	})
+	assert.NoError(t, export.Export())
+	assert.NoError(t, export.Close())
+
+	expects := map[string]string{
+		filepath.Join(root, "year=2023", "month=01", "part-0.csv"): "id\n1\n3\n6\n",
+		filepath.Join(root, "year=2023", "month=02", "part-0.csv"): "id\n2\n5\n",
+		filepath.Join(root, "year=2022", "month=12", "part-0.csv"): "id\n4\n",
+	}
+
+	assert.Len(t, export.Files(), len(expects))
+	for file, expected := range expects {
+		payload, err := os.ReadFile(file)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, string(payload))
+	}
+}
+
+func TestShouldExportRowsOfAnyStorageWithSuccess(t *testing.T) {
+	rows := storage.NewRows([]storage.Column{{Name: "id", Type: "BIGINT"}, {Name: "name", Type: "VARCHAR"}}, [][]any{
+		{int64(1), "name_1"},
+		{int64(2), nil},
+	})
+
+	exportPath := filepath.Join(t.TempDir(), "result.csv")
+	export := exportdata.NewRowsExport(rows, exportPath, progressbar.NewOptions(0, progressbar.OptionSetWriter(io.Discard)), csv.NewCsvEncoder, exportdata.Options{})
+	assert.NoError(t, export.Export())
+	assert.NoError(t, export.Close())
+	assert.Equal(t, int64(2), export.Rows())
+
+	payload, err := os.ReadFile(exportPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "id,name\n1,name_1\n2,\n", string(payload))
+}
+
+func TestShouldAppendExportWithSuccess(t *testing.T) {
+	tests := []struct {
+		existing string
+		query    string
+		fails    bool
+		expects  string
+	}{
+		{
+			existing: "id,name\n1,name_1\n",
+			query:    "select * from rows;",
+			expects:  "id,name\n1,name_1\n2,name_2\n",
+		},
+		{
+			existing: "",
+			query:    "select * from rows;",
+			expects:  "id,name\n2,name_2\n",
+		},
+		{
+			existing: "id,name\n1,name_1\n",
+			query:    "select name from rows;",
+			fails:    true,
+			expects:  "id,name\n1,name_1\n",
+		},
+	}
+
+	for _, test := range tests {
+		storage, err := sqlite.NewSqLiteStorage(":memory:")
+		assert.NoError(t, err)
+
+		columns := []string{"id", "name"}
+		assert.NoError(t, storage.BuildStructure("rows", columns))
+		assert.NoError(t, storage.InsertRow("rows", columns, []any{"2", "name_2"}))
+
+		rows, err := storage.Query(test.query)
+		assert.NoError(t, err)
+
+		exportPath := filepath.Join(t.TempDir(), "result.csv")
+		if test.existing != "" {
+			assert.NoError(t, os.WriteFile(exportPath, []byte(test.existing), 0644))
+		}
+
+		export := exportdata.NewRowsExport(rows, exportPath, progressbar.NewOptions(0, progressbar.OptionSetWriter(io.Discard)), csv.NewCsvEncoder, exportdata.Options{
+			Mode: exportdata.AppendMode,
+		})
+
+		err = export.Export()
+		assert.Equal(t, test.fails, err != nil)
+		assert.NoError(t, export.Close())
+
+		payload, err := os.ReadFile(exportPath)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expects, string(payload))
+
+		assert.NoError(t, rows.Close())
+		assert.NoError(t, storage.Close())
+	}
+}
@@ -0,0 +1,178 @@
+package json
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/exportdata"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	indentDefault = "  "
+	groupRowsKey  = "rows"
+)
+
+type jsonEncoder struct {
+	w       io.Writer
+	columns []string
+	groupBy []string
+	pretty  bool
+	total   int
+	groups  []*group
+	index   map[string]*group
+}
+
+// group aggregate rows sharing the same values on the group by columns
+type group struct {
+	attrs map[string]interface{}
+	rows  []map[string]interface{}
+}
+
+func NewJsonEncoder(w io.Writer, pretty bool, groupBy []string) exportdata.Encoder {
+	return &jsonEncoder{w: w, pretty: pretty, groupBy: groupBy, index: map[string]*group{}}
+}
+
+// WriteHeader open array
+func (j *jsonEncoder) WriteHeader(columns []string) error {
+	j.columns = columns
+
+	if err := j.validateGroupBy(); err != nil {
+		return err
+	}
+
+	return j.write("[")
+}
+
+// WriteRow append row to the array, when grouping the row is kept until Flush
+func (j *jsonEncoder) WriteRow(values []any) error {
+	attr := map[string]interface{}{}
+	for i, c := range j.columns {
+		attr[c] = values[i]
+	}
+
+	if len(j.groupBy) > 0 {
+		return j.appendGroup(attr)
+	}
+
+	return j.writeElement(attr)
+}
+
+// Flush write grouped rows and finish array
+func (j *jsonEncoder) Flush() error {
+	for _, g := range j.groups {
+		g.attrs[groupRowsKey] = g.rows
+		if err := j.writeElement(g.attrs); err != nil {
+			return err
+		}
+	}
+
+	j.groups = nil
+
+	closing := "]\n"
+	if j.pretty && j.total > 0 {
+		closing = "\n]\n"
+	}
+
+	return j.write(closing)
+}
+
+// appendGroup nest row under the group by columns, keeping the order in which each group first appears
+func (j *jsonEncoder) appendGroup(attr map[string]interface{}) error {
+	key, err := j.groupKey(attr)
+	if err != nil {
+		return err
+	}
+
+	g, ok := j.index[key]
+	if !ok {
+		g = &group{attrs: map[string]interface{}{}, rows: make([]map[string]interface{}, 0)}
+		for _, c := range j.groupBy {
+			g.attrs[c] = attr[c]
+		}
+		j.index[key] = g
+		j.groups = append(j.groups, g)
+	}
+
+	for _, c := range j.groupBy {
+		delete(attr, c)
+	}
+
+	g.rows = append(g.rows, attr)
+
+	return nil
+}
+
+// groupKey build a key identifying the group of the row
+func (j *jsonEncoder) groupKey(attr map[string]interface{}) (string, error) {
+	values := make([]interface{}, 0, len(j.groupBy))
+	for _, c := range j.groupBy {
+		values = append(values, attr[c])
+	}
+
+	key, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("failed to build group key: %w", err)
+	}
+
+	return string(key), nil
+}
+
+// writeElement append element to the array
+func (j *jsonEncoder) writeElement(element interface{}) error {
+	payload, err := j.marshal(element)
+	if err != nil {
+		return fmt.Errorf("failed to serialize row: %w", err)
+	}
+
+	var raw strings.Builder
+	if j.total > 0 {
+		raw.WriteString(",")
+	}
+
+	if j.pretty {
+		raw.WriteString("\n" + indentDefault)
+	}
+
+	raw.Write(payload)
+	j.total++
+
+	return j.write(raw.String())
+}
+
+// write append content
+func (j *jsonEncoder) write(content string) error {
+	if _, err := io.WriteString(j.w, content); err != nil {
+		return fmt.Errorf("failed to write content: %w", err)
+	}
+
+	return nil
+}
+
+// marshal serialize element as compact or indented json
+func (j *jsonEncoder) marshal(element interface{}) ([]byte, error) {
+	if j.pretty {
+		return json.MarshalIndent(element, indentDefault, indentDefault)
+	}
+
+	return json.Marshal(element)
+}
+
+// validateGroupBy check that all group by columns are present in the result
+func (j *jsonEncoder) validateGroupBy() error {
+	for _, g := range j.groupBy {
+		found := false
+		for _, c := range j.columns {
+			if c == g {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return fmt.Errorf("group by column %s not found in result", g)
+		}
+	}
+
+	return nil
+}
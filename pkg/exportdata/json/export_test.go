@@ -0,0 +1,61 @@
+package json_test
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/exportdata/json"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestShouldEncodeJsonWithSuccess(t *testing.T) {
+	tests := []struct {
+		columns []string
+		rows    [][]any
+		pretty  bool
+		groupBy []string
+		expects string
+	}{
+		{
+			columns: []string{"id", "name"},
+			rows:    [][]any{{"1", "name_1"}, {"2", "name_2"}},
+			expects: "[{\"id\":\"1\",\"name\":\"name_1\"},{\"id\":\"2\",\"name\":\"name_2\"}]\n",
+		},
+		{
+			columns: []string{"id", "name"},
+			rows:    [][]any{},
+			pretty:  true,
+			expects: "[]\n",
+		},
+		{
+			columns: []string{"id"},
+			rows:    [][]any{{"1"}},
+			pretty:  true,
+			expects: "[\n  {\n    \"id\": \"1\"\n  }\n]\n",
+		},
+		{
+			columns: []string{"customer_id", "order_id"},
+			rows:    [][]any{{"2", "10"}, {"1", "11"}, {"2", "12"}},
+			groupBy: []string{"customer_id"},
+			expects: "[{\"customer_id\":\"2\",\"rows\":[{\"order_id\":\"10\"},{\"order_id\":\"12\"}]}," +
+				"{\"customer_id\":\"1\",\"rows\":[{\"order_id\":\"11\"}]}]\n",
+		},
+	}
+
+	for _, test := range tests {
+		buf := new(bytes.Buffer)
+		encoder := json.NewJsonEncoder(buf, test.pretty, test.groupBy)
+
+		assert.NoError(t, encoder.WriteHeader(test.columns))
+		for _, row := range test.rows {
+			assert.NoError(t, encoder.WriteRow(row))
+		}
+		assert.NoError(t, encoder.Flush())
+
+		assert.Equal(t, test.expects, buf.String())
+	}
+}
+
+func TestShouldFailWhenGroupByColumnIsMissing(t *testing.T) {
+	encoder := json.NewJsonEncoder(new(bytes.Buffer), false, []string{"customer_id"})
+	assert.Error(t, encoder.WriteHeader([]string{"id"}))
+}
@@ -3,74 +3,37 @@ package jsonl
 import (
 	"adrianolaselva.github.io/csvql/pkg/exportdata"
 	"bytes"
-	"database/sql"
 	"encoding/json"
 	"fmt"
-	"github.com/schollz/progressbar/v3"
-	"os"
-	"path/filepath"
+	"io"
 )
 
-const (
-	fileModeDefault os.FileMode = 0644
-)
-
-type jsonlExport struct {
-	rows       *sql.Rows
-	bar        *progressbar.ProgressBar
-	file       *os.File
-	exportPath string
-	columns    []string
+type jsonlEncoder struct {
+	w       io.Writer
+	columns []string
 }
 
-func NewJsonlExport(rows *sql.Rows, exportPath string, bar *progressbar.ProgressBar) exportdata.Export {
-	return &jsonlExport{rows: rows, exportPath: exportPath, bar: bar}
+func NewJsonlEncoder(w io.Writer) exportdata.Encoder {
+	return &jsonlEncoder{w: w}
 }
 
-// Export rows in file
-func (j *jsonlExport) Export() error {
-	if err := j.loadColumns(); err != nil {
-		return fmt.Errorf("failed to load columns: %w", err)
-	}
-
-	if err := j.openFile(); err != nil {
-		return fmt.Errorf("failed to open file: %w", err)
-	}
-
-	for j.rows.Next() {
-		_ = j.bar.Add(1)
-		if err := j.readAndAppendFile(); err != nil {
-			return fmt.Errorf("failed to read and append line in file: %w", err)
-		}
-	}
+// WriteHeader keep columns used as attributes
+func (j *jsonlEncoder) WriteHeader(columns []string) error {
+	j.columns = columns
 
 	return nil
 }
 
-// Close execute in defer
-func (j *jsonlExport) Close() error {
-	defer func(file *os.File) {
-		_ = file.Close()
-	}(j.file)
-
-	return nil
+// AppendHeader keep columns used as attributes, json lines can always be appended
+func (j *jsonlEncoder) AppendHeader(_ io.Reader, columns []string) error {
+	return j.WriteHeader(columns)
 }
 
-// readAndAppendFile read line and append in file
-func (j *jsonlExport) readAndAppendFile() error {
-	values := make([]interface{}, len(j.columns))
-	pointers := make([]interface{}, len(j.columns))
-	for i := range values {
-		pointers[i] = &values[i]
-	}
-
-	if err := j.rows.Scan(pointers...); err != nil {
-		return fmt.Errorf("failed to load row: %w", err)
-	}
-
+// WriteRow write row as json line
+func (j *jsonlEncoder) WriteRow(values []any) error {
 	attr := map[string]interface{}{}
 	for i, c := range j.columns {
-		attr[c] = pointers[i]
+		attr[c] = values[i]
 	}
 
 	payload, err := json.Marshal(attr)
@@ -83,44 +46,15 @@ func (j *jsonlExport) readAndAppendFile() error {
 		return fmt.Errorf("failed to compact payload: %w", err)
 	}
 
-	if _, err := j.file.WriteString(fmt.Sprintf("%s\n", buffer.String())); err != nil {
-		return fmt.Errorf("failed to write file %s: %w", j.exportPath, err)
-	}
-
-	return nil
-}
-
-// openFile open file
-func (j *jsonlExport) openFile() error {
-	if _, err := os.Stat(j.exportPath); !os.IsNotExist(err) {
-		err := os.Remove(j.exportPath)
-		if err != nil {
-			return fmt.Errorf("failed to remove file: %w", err)
-		}
-	}
-
-	if err := os.MkdirAll(filepath.Dir(j.exportPath), os.ModePerm); err != nil {
-		return fmt.Errorf("failed to create path: %w", err)
+	buffer.WriteString("\n")
+	if _, err := buffer.WriteTo(j.w); err != nil {
+		return fmt.Errorf("failed to write row: %w", err)
 	}
 
-	file, err := os.OpenFile(j.exportPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, fileModeDefault)
-	if err != nil {
-		return fmt.Errorf("failed to open file %s: %w", j.exportPath, err)
-	}
-
-	j.file = file
-
 	return nil
 }
 
-// loadColumns load columns
-func (j *jsonlExport) loadColumns() error {
-	columns, err := j.rows.Columns()
-	if err != nil {
-		return fmt.Errorf("failed to load columns: %w", err)
-	}
-
-	j.columns = columns
-
+// Flush nothing to be done, rows are written as they are encoded
+func (j *jsonlEncoder) Flush() error {
 	return nil
 }
@@ -0,0 +1,90 @@
+package markdown
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/exportdata"
+	"fmt"
+	"io"
+	"strings"
+)
+
+var cellReplacer = strings.NewReplacer("|", "\\|", "\r\n", "<br>", "\n", "<br>")
+
+type markdownEncoder struct {
+	w io.Writer
+}
+
+func NewMarkdownEncoder(w io.Writer) exportdata.Encoder {
+	return &markdownEncoder{w: w}
+}
+
+// WriteHeader write table header and alignment line
+func (m *markdownEncoder) WriteHeader(columns []string) error {
+	cells := make([]any, 0, len(columns))
+	separators := make([]any, 0, len(columns))
+	for _, c := range columns {
+		cells = append(cells, c)
+		separators = append(separators, "---")
+	}
+
+	if err := m.writeLine(cells); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(m.w, m.formatLine(separators, false))
+	if err != nil {
+		return fmt.Errorf("failed to write headers: %w", err)
+	}
+
+	return nil
+}
+
+// WriteRow write row as table line
+func (m *markdownEncoder) WriteRow(values []any) error {
+	return m.writeLine(values)
+}
+
+// Flush nothing to be done, rows are written as they are encoded
+func (m *markdownEncoder) Flush() error {
+	return nil
+}
+
+// writeLine write escaped cells
+func (m *markdownEncoder) writeLine(values []any) error {
+	if _, err := io.WriteString(m.w, m.formatLine(values, true)); err != nil {
+		return fmt.Errorf("failed to write row: %w", err)
+	}
+
+	return nil
+}
+
+// formatLine format cells as table line
+func (m *markdownEncoder) formatLine(values []any, escape bool) string {
+	var line strings.Builder
+	line.WriteString("|")
+	for _, v := range values {
+		cell := m.toString(v)
+		if escape {
+			cell = cellReplacer.Replace(cell)
+		}
+
+		line.WriteString(" " + cell + " |")
+	}
+
+	line.WriteString("\n")
+
+	return line.String()
+}
+
+// toString format value as text
+func (m *markdownEncoder) toString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,172 @@
+package template
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/exportdata"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"text/template"
+	"time"
+)
+
+const (
+	HeaderSection = "header"
+	RowSection    = "row"
+	FooterSection = "footer"
+)
+
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05",
+	"2006-01-02T15:04:05",
+	"2006-01-02",
+	"02/01/2006 15:04:05",
+	"02/01/2006",
+}
+
+// Document data available in header and footer sections
+type Document struct {
+	Columns []string
+	Rows    int
+}
+
+type templateEncoder struct {
+	w        io.Writer
+	tmpl     *template.Template
+	row      *template.Template
+	document Document
+}
+
+// Parse load template file, sections are declared with `{{define "header"}}`, `{{define "row"}}` and
+// `{{define "footer"}}`, when no row section is declared the whole file is used as row
+func Parse(templatePath string) (*template.Template, error) {
+	tmpl, err := template.New(filepath.Base(templatePath)).Funcs(Funcs()).ParseFiles(templatePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template %s: %w", templatePath, err)
+	}
+
+	return tmpl, nil
+}
+
+// Funcs helper functions available in templates
+func Funcs() template.FuncMap {
+	return template.FuncMap{
+		"upper":    func(v any) string { return strings.ToUpper(toString(v)) },
+		"lower":    func(v any) string { return strings.ToLower(toString(v)) },
+		"trim":     func(v any) string { return strings.TrimSpace(toString(v)) },
+		"join":     strings.Join,
+		"json":     toJSON,
+		"sqlQuote": sqlQuote,
+		"date":     formatDate,
+		"now":      time.Now,
+	}
+}
+
+func NewTemplateEncoder(w io.Writer, tmpl *template.Template) exportdata.Encoder {
+	row := tmpl.Lookup(RowSection)
+	if row == nil {
+		row = tmpl
+	}
+
+	return &templateEncoder{w: w, tmpl: tmpl, row: row}
+}
+
+// WriteHeader execute header section
+func (t *templateEncoder) WriteHeader(columns []string) error {
+	t.document = Document{Columns: columns}
+
+	return t.executeSection(HeaderSection)
+}
+
+// WriteRow execute row section with the row as map of column and value
+func (t *templateEncoder) WriteRow(values []any) error {
+	attr := map[string]interface{}{}
+	for i, c := range t.document.Columns {
+		attr[c] = values[i]
+	}
+
+	if err := t.row.Execute(t.w, attr); err != nil {
+		return fmt.Errorf("failed to execute row template: %w", err)
+	}
+
+	t.document.Rows++
+
+	return nil
+}
+
+// Flush execute footer section
+func (t *templateEncoder) Flush() error {
+	return t.executeSection(FooterSection)
+}
+
+// executeSection execute optional section with the document data
+func (t *templateEncoder) executeSection(name string) error {
+	section := t.tmpl.Lookup(name)
+	if section == nil {
+		return nil
+	}
+
+	if err := section.Execute(t.w, t.document); err != nil {
+		return fmt.Errorf("failed to execute %s template: %w", name, err)
+	}
+
+	return nil
+}
+
+// toString format value as text
+func toString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// toJSON serialize value as json
+func toJSON(value any) (string, error) {
+	if v, ok := value.([]byte); ok {
+		value = string(v)
+	}
+
+	payload, err := json.Marshal(value)
+	if err != nil {
+		return "", fmt.Errorf("failed to serialize value: %w", err)
+	}
+
+	return string(payload), nil
+}
+
+// sqlQuote format value as sql literal
+func sqlQuote(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case int64, float64, bool:
+		return fmt.Sprintf("%v", v)
+	default:
+		return "'" + strings.ReplaceAll(toString(v), "'", "''") + "'"
+	}
+}
+
+// formatDate parse value using common layouts and format it with the given layout
+func formatDate(layout string, value any) (string, error) {
+	if v, ok := value.(time.Time); ok {
+		return v.Format(layout), nil
+	}
+
+	raw := toString(value)
+	for _, l := range dateLayouts {
+		if parsed, err := time.Parse(l, raw); err == nil {
+			return parsed.Format(layout), nil
+		}
+	}
+
+	return "", fmt.Errorf("failed to parse date %s", raw)
+}
@@ -0,0 +1,53 @@
+package template_test
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/exportdata/template"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShouldEncodeTemplateWithSuccess(t *testing.T) {
+	tests := []struct {
+		template string
+		columns  []string
+		rows     [][]any
+		expects  string
+	}{
+		{
+			template: `{{define "header"}}INSERT INTO users ({{join .Columns ", "}}) VALUES{{"\n"}}{{end}}` +
+				`{{define "row"}}({{sqlQuote .id}}, {{sqlQuote .name}}),{{"\n"}}{{end}}` +
+				`{{define "footer"}}-- {{.Rows}} rows{{end}}`,
+			columns: []string{"id", "name"},
+			rows:    [][]any{{int64(1), "O'Neil"}, {int64(2), nil}},
+			expects: "INSERT INTO users (id, name) VALUES\n(1, 'O''Neil'),\n(2, NULL),\n-- 2 rows",
+		},
+		{
+			template: `{{upper .name}} {{lower .code}} {{json .name}} {{date "02/01/2006" .created_at}}{{"\n"}}`,
+			columns:  []string{"name", "code", "created_at"},
+			rows:     [][]any{{"john", "ABC", "2023-02-01"}},
+			expects:  "JOHN abc \"john\" 01/02/2023\n",
+		},
+	}
+
+	for _, test := range tests {
+		templatePath := filepath.Join(t.TempDir(), "export.tmpl")
+		assert.NoError(t, os.WriteFile(templatePath, []byte(test.template), 0644))
+
+		tmpl, err := template.Parse(templatePath)
+		assert.NoError(t, err)
+
+		buf := new(bytes.Buffer)
+		encoder := template.NewTemplateEncoder(buf, tmpl)
+
+		assert.NoError(t, encoder.WriteHeader(test.columns))
+		for _, row := range test.rows {
+			assert.NoError(t, encoder.WriteRow(row))
+		}
+		assert.NoError(t, encoder.Flush())
+
+		assert.Equal(t, test.expects, buf.String())
+	}
+}
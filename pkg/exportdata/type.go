@@ -1,6 +1,27 @@
 package exportdata
 
+import "io"
+
 type Export interface {
 	Export() error
+	Files() []string
+	Rows() int64
+	Bytes() int64
 	Close() error
 }
+
+// Encoder serialize rows of a result in a specific format
+type Encoder interface {
+	WriteHeader(columns []string) error
+	WriteRow(values []any) error
+	Flush() error
+}
+
+// EncoderFactory build a new encoder writing in w, called for each produced file
+type EncoderFactory func(w io.Writer) Encoder
+
+// Appendable implemented by encoders able to append rows to an existing file, the existing content is validated
+// instead of writing the header again
+type Appendable interface {
+	AppendHeader(existing io.Reader, columns []string) error
+}
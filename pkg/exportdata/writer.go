@@ -0,0 +1,356 @@
+package exportdata
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"github.com/klauspost/compress/zstd"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	StdoutPath                  = "-"
+	GzipExtension               = ".gz"
+	ZstdExtension               = ".zst"
+	fileModeDefault os.FileMode = 0644
+)
+
+// WriteMode behavior when the export path already exists
+type WriteMode string
+
+const (
+	OverwriteMode WriteMode = "overwrite"
+	AppendMode    WriteMode = "append"
+	NoClobberMode WriteMode = "no-clobber"
+)
+
+// Output destination of an export, content is written in a temporary file moved to the export path on Commit,
+// so a failed export never destroys a previous file
+type Output interface {
+	io.Writer
+	Path() string
+	Appending() bool
+	Close() error
+	Reopen() error
+	Commit() error
+	Abort() error
+}
+
+type fileOutput struct {
+	exportPath string
+	writePath  string
+	mode       WriteMode
+	size       int64
+	writer     io.WriteCloser
+}
+
+type stdoutOutput struct{}
+
+// compressedWriter compress content before writing in the underlying file
+type compressedWriter struct {
+	io.WriteCloser
+	file *os.File
+}
+
+// decompressedReader decompress content read from the underlying file
+type decompressedReader struct {
+	io.Reader
+	file  *os.File
+	close func()
+}
+
+// IsStdout check if export path targets stdout
+func IsStdout(exportPath string) bool {
+	return exportPath == StdoutPath
+}
+
+// SplitPath build the path of the n-th file of a split export, `result.csv.gz` becomes `result-0001.csv.gz`
+func SplitPath(exportPath string, n int) string {
+	dir, name := filepath.Split(exportPath)
+	ext := filepath.Ext(name)
+	switch strings.ToLower(ext) {
+	case GzipExtension, ZstdExtension:
+		ext = filepath.Ext(strings.TrimSuffix(name, ext)) + ext
+	}
+
+	return filepath.Join(dir, fmt.Sprintf("%s-%04d%s", strings.TrimSuffix(name, ext), n, ext))
+}
+
+// OpenOutput open export destination, `-` streams to stdout and `.gz`/`.zst` extensions are compressed on the fly
+func OpenOutput(exportPath string, mode WriteMode) (Output, error) {
+	if IsStdout(exportPath) {
+		return &stdoutOutput{}, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(exportPath), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("failed to create path: %w", err)
+	}
+
+	output := &fileOutput{exportPath: exportPath, mode: mode, size: -1}
+
+	stat, err := os.Stat(exportPath)
+	switch {
+	case err == nil && mode == NoClobberMode:
+		return nil, fmt.Errorf("file %s already exists", exportPath)
+	case err == nil && mode == AppendMode:
+		output.size = stat.Size()
+	case err != nil && !os.IsNotExist(err):
+		return nil, fmt.Errorf("failed to check file %s: %w", exportPath, err)
+	}
+
+	if err := output.open(); err != nil {
+		return nil, err
+	}
+
+	return output, nil
+}
+
+// OpenReader open export file for reading, decompressing `.gz`/`.zst` files
+func OpenReader(exportPath string) (io.ReadCloser, error) {
+	file, err := os.Open(exportPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s: %w", exportPath, err)
+	}
+
+	switch strings.ToLower(filepath.Ext(exportPath)) {
+	case GzipExtension:
+		r, err := gzip.NewReader(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to initialize gzip decoder: %w", err)
+		}
+
+		return &decompressedReader{Reader: r, file: file, close: func() { _ = r.Close() }}, nil
+	case ZstdExtension:
+		r, err := zstd.NewReader(file)
+		if err != nil {
+			_ = file.Close()
+			return nil, fmt.Errorf("failed to initialize zstd decoder: %w", err)
+		}
+
+		return &decompressedReader{Reader: r, file: file, close: r.Close}, nil
+	}
+
+	return file, nil
+}
+
+// Write write content in the temporary file or in the export file when appending
+func (f *fileOutput) Write(p []byte) (int, error) {
+	if f.writer == nil {
+		return 0, fmt.Errorf("file %s is closed", f.exportPath)
+	}
+
+	n, err := f.writer.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("failed to write file %s: %w", f.exportPath, err)
+	}
+
+	return n, nil
+}
+
+// Path return export path
+func (f *fileOutput) Path() string {
+	return f.exportPath
+}
+
+// Appending check if content is appended to an existing non-empty file
+func (f *fileOutput) Appending() bool {
+	return f.size > 0
+}
+
+// Close release file handle keeping written content
+func (f *fileOutput) Close() error {
+	if f.writer == nil {
+		return nil
+	}
+
+	writer := f.writer
+	f.writer = nil
+
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to close file %s: %w", f.exportPath, err)
+	}
+
+	return nil
+}
+
+// Reopen open released file to keep appending content, compressed files receive a new compression frame
+func (f *fileOutput) Reopen() error {
+	if f.writer != nil {
+		return nil
+	}
+
+	file, err := os.OpenFile(f.writePath, os.O_APPEND|os.O_WRONLY, fileModeDefault)
+	if err != nil {
+		return fmt.Errorf("failed to reopen file %s: %w", f.exportPath, err)
+	}
+
+	return f.wrapCompression(file)
+}
+
+// Commit move temporary file to the export path
+func (f *fileOutput) Commit() error {
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	switch f.mode {
+	case AppendMode:
+		return nil
+	case NoClobberMode:
+		if err := os.Link(f.writePath, f.exportPath); err != nil {
+			return fmt.Errorf("failed to create file %s: %w", f.exportPath, err)
+		}
+
+		return f.removeTemporary()
+	default:
+		if err := os.Rename(f.writePath, f.exportPath); err != nil {
+			return fmt.Errorf("failed to replace file %s: %w", f.exportPath, err)
+		}
+	}
+
+	return nil
+}
+
+// Abort discard written content, appended files are truncated to their original size
+func (f *fileOutput) Abort() error {
+	closeErr := f.Close()
+
+	switch {
+	case f.mode == AppendMode && f.size >= 0:
+		if err := os.Truncate(f.writePath, f.size); err != nil {
+			return fmt.Errorf("failed to restore file %s: %w", f.exportPath, err)
+		}
+	default:
+		if err := f.removeTemporary(); err != nil {
+			return err
+		}
+	}
+
+	return closeErr
+}
+
+// open open file where content is written, a temporary file next to the export path unless appending
+func (f *fileOutput) open() error {
+	if f.mode == AppendMode {
+		f.writePath = f.exportPath
+		file, err := os.OpenFile(f.writePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, fileModeDefault)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", f.exportPath, err)
+		}
+
+		return f.wrapCompression(file)
+	}
+
+	dir, name := filepath.Split(f.exportPath)
+	file, err := os.CreateTemp(dir, fmt.Sprintf(".%s.*.tmp", name))
+	if err != nil {
+		return fmt.Errorf("failed to create temporary file for %s: %w", f.exportPath, err)
+	}
+
+	f.writePath = file.Name()
+	if err := file.Chmod(fileModeDefault); err != nil {
+		_ = file.Close()
+		_ = os.Remove(f.writePath)
+		return fmt.Errorf("failed to change file mode %s: %w", f.writePath, err)
+	}
+
+	return f.wrapCompression(file)
+}
+
+// removeTemporary remove temporary file
+func (f *fileOutput) removeTemporary() error {
+	if err := os.Remove(f.writePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove temporary file %s: %w", f.writePath, err)
+	}
+
+	return nil
+}
+
+// wrapCompression wrap file with the compression detected by the export path extension
+func (f *fileOutput) wrapCompression(file *os.File) error {
+	switch strings.ToLower(filepath.Ext(f.exportPath)) {
+	case GzipExtension:
+		f.writer = &compressedWriter{WriteCloser: gzip.NewWriter(file), file: file}
+	case ZstdExtension:
+		encoder, err := zstd.NewWriter(file)
+		if err != nil {
+			_ = file.Close()
+			return fmt.Errorf("failed to initialize zstd encoder: %w", err)
+		}
+
+		f.writer = &compressedWriter{WriteCloser: encoder, file: file}
+	default:
+		f.writer = file
+	}
+
+	return nil
+}
+
+// Write write in stdout
+func (s *stdoutOutput) Write(p []byte) (int, error) {
+	n, err := os.Stdout.Write(p)
+	if err != nil {
+		return n, fmt.Errorf("failed to write stdout: %w", err)
+	}
+
+	return n, nil
+}
+
+// Path return stdout path
+func (s *stdoutOutput) Path() string {
+	return StdoutPath
+}
+
+// Appending stdout never has previous content
+func (s *stdoutOutput) Appending() bool {
+	return false
+}
+
+// Close keep stdout open
+func (s *stdoutOutput) Close() error {
+	return nil
+}
+
+// Reopen stdout is never released
+func (s *stdoutOutput) Reopen() error {
+	return nil
+}
+
+// Commit nothing to be done, content is already written
+func (s *stdoutOutput) Commit() error {
+	return nil
+}
+
+// Abort nothing to be done, content is already written
+func (s *stdoutOutput) Abort() error {
+	return nil
+}
+
+// Close flush compressed content and close file
+func (c *compressedWriter) Close() error {
+	if err := c.WriteCloser.Close(); err != nil {
+		_ = c.file.Close()
+		return fmt.Errorf("failed to finish compression: %w", err)
+	}
+
+	if err := c.file.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+
+	return nil
+}
+
+// Close release decoder and close file
+func (d *decompressedReader) Close() error {
+	d.close()
+
+	if err := d.file.Close(); err != nil {
+		return fmt.Errorf("failed to close file: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,76 @@
+package exportdata_test
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/exportdata"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShouldWriteCompressedFileWithSuccess(t *testing.T) {
+	for _, fileName := range []string{"result.csv", "result.csv.gz", "result.csv.zst"} {
+		exportPath := filepath.Join(t.TempDir(), fileName)
+
+		output, err := exportdata.OpenOutput(exportPath, exportdata.OverwriteMode)
+		assert.NoError(t, err)
+
+		_, err = io.WriteString(output, "id,name\n")
+		assert.NoError(t, err)
+
+		assert.NoError(t, output.Close())
+		assert.NoError(t, output.Reopen())
+
+		_, err = io.WriteString(output, "1,name_1\n")
+		assert.NoError(t, err)
+		assert.NoError(t, output.Commit())
+
+		r, err := exportdata.OpenReader(exportPath)
+		assert.NoError(t, err)
+
+		payload, err := io.ReadAll(r)
+		assert.NoError(t, err)
+		assert.Equal(t, "id,name\n1,name_1\n", string(payload))
+		assert.NoError(t, r.Close())
+	}
+}
+
+func TestShouldKeepPreviousFileWhenExportIsAborted(t *testing.T) {
+	tests := []struct {
+		mode    exportdata.WriteMode
+		expects string
+	}{
+		{mode: exportdata.OverwriteMode, expects: "id\n1\n"},
+		{mode: exportdata.AppendMode, expects: "id\n1\n"},
+	}
+
+	for _, test := range tests {
+		dir := t.TempDir()
+		exportPath := filepath.Join(dir, "result.csv")
+		assert.NoError(t, os.WriteFile(exportPath, []byte("id\n1\n"), 0644))
+
+		output, err := exportdata.OpenOutput(exportPath, test.mode)
+		assert.NoError(t, err)
+
+		_, err = io.WriteString(output, "2\n")
+		assert.NoError(t, err)
+		assert.NoError(t, output.Abort())
+
+		payload, err := os.ReadFile(exportPath)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expects, string(payload))
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	}
+}
+
+func TestShouldFailWhenFileExistsAndNoClobber(t *testing.T) {
+	exportPath := filepath.Join(t.TempDir(), "result.csv")
+	assert.NoError(t, os.WriteFile(exportPath, []byte("id\n1\n"), 0644))
+
+	_, err := exportdata.OpenOutput(exportPath, exportdata.NoClobberMode)
+	assert.Error(t, err)
+}
@@ -4,7 +4,6 @@ import (
 	"adrianolaselva.github.io/csvql/pkg/filehandler"
 	"adrianolaselva.github.io/csvql/pkg/storage"
 	"bytes"
-	"database/sql"
 	"encoding/csv"
 	"errors"
 	"fmt"
@@ -15,14 +14,25 @@ import (
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 )
 
 const (
-	bufferMaxLength = 32 * 1024
+	bufferMaxLength  = 32 * 1024
+	sqlCountTemplate = "select count(*) from %s;"
+	batchValues      = 10000
 )
 
 var nonAlphanumericRegex = regexp.MustCompile(`[^a-zA-Z0-9 ]+`)
 
+// selection columns of the file loaded into the table
+type selection struct {
+	header    []string
+	columns   []string
+	positions []int
+	stratum   int
+}
+
 type csvHandler struct {
 	mx          sync.Mutex
 	bar         *progressbar.ProgressBar
@@ -33,33 +43,76 @@ type csvHandler struct {
 	limitLines  int
 	currentLine int
 	delimiter   rune
+	refresh     bool
+	ifExists    string
+	primaryKey  []string
+	mode        string
+	indexes     []filehandler.Index
+	autoIndex   bool
+	lazy        bool
+	lazyStats   bool
+	selectCols  []string
+	where       string
+	sample      filehandler.Sample
+	sampleBy    string
+	seed        int64
+	persistent  bool
+	sampler     *filehandler.Sampler
+	summaries   []filehandler.Summary
+	canceled    int32
 }
 
-func NewCsvHandler(fileInputs []string, delimiter rune, bar *progressbar.ProgressBar, storage storage.Storage, limitLines int) filehandler.FileHandler {
-	return &csvHandler{fileInputs: fileInputs, delimiter: delimiter, storage: storage, bar: bar, limitLines: limitLines}
+func NewCsvHandler(fileInputs []string, delimiter rune, bar *progressbar.ProgressBar, storage storage.Storage, opts filehandler.Options) filehandler.FileHandler {
+	return &csvHandler{
+		fileInputs: fileInputs,
+		delimiter:  delimiter,
+		storage:    storage,
+		bar:        bar,
+		limitLines: opts.Lines,
+		refresh:    opts.Refresh,
+		ifExists:   opts.IfExists,
+		primaryKey: opts.PrimaryKey,
+		mode:       opts.Mode,
+		indexes:    opts.Indexes,
+		autoIndex:  opts.AutoIndex,
+		lazy:       opts.Lazy,
+		lazyStats:  opts.LazyStats,
+		selectCols: opts.Select,
+		where:      opts.Where,
+		sample:     opts.Sample,
+		sampleBy:   opts.SampleBy,
+		seed:       opts.Seed,
+		persistent: opts.Persistent,
+	}
 }
 
 // Import import data
 func (c *csvHandler) Import() error {
+	if len(c.fileInputs) == 0 {
+		return nil
+	}
+
 	if err := c.openFiles(); err != nil {
 		return err
 	}
 
 	wg := new(sync.WaitGroup)
-	wg.Add(len(c.fileInputs))
-	errChannels := make(chan error, len(c.fileInputs))
-
-	for _, file := range c.fileInputs {
-		go func(wg *sync.WaitGroup, file string, errChan chan error) {
-			defer wg.Done()
-			err := c.loadTotalRows(file)
-			errChan <- err
-		}(wg, file, errChannels)
-	}
+	if !c.lazy && c.fileLoader() == nil {
+		wg.Add(len(c.fileInputs))
+		errChannels := make(chan error, len(c.fileInputs))
+
+		for _, file := range c.fileInputs {
+			go func(wg *sync.WaitGroup, file string, errChan chan error) {
+				defer wg.Done()
+				err := c.loadTotalRows(file)
+				errChan <- err
+			}(wg, file, errChannels)
+		}
 
-	wg.Wait()
-	if err := <-errChannels; err != nil {
-		return err
+		wg.Wait()
+		if err := <-errChannels; err != nil {
+			return err
+		}
 	}
 
 	if c.limitLines > 0 && c.totalLines > c.limitLines {
@@ -67,12 +120,12 @@ func (c *csvHandler) Import() error {
 	}
 
 	wg.Add(len(c.files))
-	errChannels = make(chan error, len(c.files))
+	errChannels := make(chan error, len(c.files))
 	for _, file := range c.files {
 		tableName := c.formatTableName(file)
 		go func(wg *sync.WaitGroup, file *os.File, tableName string, errChan chan error) {
 			defer wg.Done()
-			errChan <- c.loadDataFromFile(tableName, file)
+			errChan <- c.importFile(tableName, file)
 		}(wg, file, tableName, errChannels)
 	}
 
@@ -81,9 +134,47 @@ func (c *csvHandler) Import() error {
 		return err
 	}
 
+	for _, index := range c.indexes {
+		if err := c.storage.CreateIndex(index.Table, index.Columns, false); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// ImportFile import a single file into tableName, when empty the table name is built from the file name
+func (c *csvHandler) ImportFile(fileInput string, tableName string) (string, error) {
+	file, err := os.Open(fileInput)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file: %w", err)
+	}
+
+	c.files = append(c.files, file)
+	c.fileInputs = append(c.fileInputs, fileInput)
+
+	if !c.lazy && c.fileLoader() == nil {
+		if err := c.loadTotalRows(fileInput); err != nil {
+			return "", err
+		}
+	}
+
+	if c.limitLines > 0 && c.totalLines > c.limitLines {
+		c.totalLines = c.limitLines
+	}
+
+	if tableName == "" {
+		tableName = c.formatTableName(file)
+	}
+
+	c.bar.Reset()
+	if err := c.importFile(tableName, file); err != nil {
+		return "", err
+	}
+
+	return tableName, nil
+}
+
 // formatTableName format table name by removing invalid characters
 func (c *csvHandler) formatTableName(file *os.File) string {
 	tableName := strings.ReplaceAll(strings.ToLower(filepath.Base(file.Name())), filepath.Ext(file.Name()), "")
@@ -92,13 +183,23 @@ func (c *csvHandler) formatTableName(file *os.File) string {
 }
 
 // Query execute statements
-func (c *csvHandler) Query(cmd string) (*sql.Rows, error) {
-	rows, err := c.storage.Query(cmd)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
-	}
+func (c *csvHandler) Query(cmd string) (storage.Rows, error) {
+	return c.storage.Query(cmd)
+}
+
+// Summaries rows inserted, updated and unchanged by upsert imports and files skipped as unchanged
+func (c *csvHandler) Summaries() []filehandler.Summary {
+	return c.summaries
+}
 
-	return rows, nil
+// Cancel stop the running import after the row being read, files loaded natively by the storage stop once loaded
+func (c *csvHandler) Cancel() {
+	atomic.StoreInt32(&c.canceled, 1)
+}
+
+// isCanceled check if the import was canceled
+func (c *csvHandler) isCanceled() bool {
+	return atomic.LoadInt32(&c.canceled) == 1
 }
 
 // Lines return total lines
@@ -121,24 +222,192 @@ func (c *csvHandler) Close() error {
 	return nil
 }
 
-// loadDataFromFile load data from file
-func (c *csvHandler) loadDataFromFile(tableName string, file *os.File) error {
+// importFile load file into table, skipping it when unchanged since the last import recorded in a persistent
+// storage, existing tables are replaced, appended, skipped or fail the import according to the if exists mode
+func (c *csvHandler) importFile(tableName string, file *os.File) error {
 	c.mx.Lock()
 	defer c.mx.Unlock()
 
+	if c.isCanceled() {
+		return filehandler.ErrImportCanceled
+	}
+
+	path, err := filepath.Abs(file.Name())
+	if err != nil {
+		return fmt.Errorf("failed to resolve path of file %s: %w", file.Name(), err)
+	}
+
+	if c.lazy {
+		return c.createVirtualTable(tableName, path)
+	}
+
+	var current storage.Fingerprint
+	if c.persistent {
+		if current, err = filehandler.Fingerprint(path, c.importOptions()); err != nil {
+			return err
+		}
+
+		unchanged, err := c.unchangedSinceImport(tableName, &current)
+		if err != nil {
+			return err
+		}
+
+		if unchanged {
+			c.summaries = append(c.summaries, filehandler.Summary{Table: tableName, Path: path, Skipped: true})
+			return nil
+		}
+	}
+
+	ok, err := c.prepareTable(tableName)
+	if err != nil || !ok {
+		return err
+	}
+
+	if loader := c.fileLoader(); loader != nil {
+		err = c.loadNatively(loader, tableName, path)
+	} else {
+		err = c.loadDataFromFile(tableName, file)
+	}
+
+	if err != nil || !c.persistent {
+		return err
+	}
+
+	if current.Hash == "" {
+		if current.Hash, err = filehandler.Hash(path); err != nil {
+			return err
+		}
+	}
+
+	return c.storage.SaveFingerprint(tableName, current)
+}
+
+// unchangedSinceImport check if the file matches the fingerprint recorded by the last import into the table and the
+// table still exists, size and modification time are compared first and the file is only hashed when the
+// modification time changed
+func (c *csvHandler) unchangedSinceImport(tableName string, current *storage.Fingerprint) (bool, error) {
+	recorded, ok, err := c.storage.LoadFingerprint(tableName, current.Path)
+	if err != nil || !ok || c.refresh {
+		return false, err
+	}
+
+	if exists, err := c.storage.HasTable(tableName); err != nil || !exists {
+		return false, err
+	}
+
+	unchanged, err := filehandler.Unchanged(recorded, current)
+	if err != nil || !unchanged {
+		return false, err
+	}
+
+	if recorded.ModTime == current.ModTime {
+		return true, nil
+	}
+
+	return true, c.storage.SaveFingerprint(tableName, *current)
+}
+
+// fileLoader storage loading files natively, nil when rows are read to be limited, selected, filtered, sampled
+// or upserted
+func (c *csvHandler) fileLoader() storage.FileLoader {
+	loader, ok := c.storage.(storage.FileLoader)
+	if !ok || c.limitLines > 0 || len(c.selectCols) > 0 || c.where != "" || c.sample.Enabled() || c.mode == filehandler.UpsertMode {
+		return nil
+	}
+
+	return loader
+}
+
+// loadNatively load file through the storage, then create primary key and indexes
+func (c *csvHandler) loadNatively(loader storage.FileLoader, tableName string, path string) error {
+	columns, err := loader.LoadFile(tableName, path, c.delimiter)
+	if err != nil {
+		return err
+	}
+
+	if err := c.createPrimaryKey(tableName, columns); err != nil {
+		return err
+	}
+
+	return c.createAutoIndexes(tableName, columns)
+}
+
+// createVirtualTable create table reading the file on each query instead of loading it
+func (c *csvHandler) createVirtualTable(tableName string, path string) error {
+	ok, err := c.prepareTable(tableName)
+	if err != nil || !ok {
+		return err
+	}
+
+	return c.storage.CreateVirtualTable(tableName, path, c.delimiter, c.lazyStats)
+}
+
+// prepareTable apply the if exists mode to an existing table, ok is false when the import is skipped
+func (c *csvHandler) prepareTable(tableName string) (bool, error) {
+	exists, err := c.storage.HasTable(tableName)
+	if err != nil {
+		return false, err
+	}
+
+	if !exists {
+		return true, nil
+	}
+
+	switch c.ifExists {
+	case filehandler.SkipIfExists:
+		return false, nil
+	case filehandler.FailIfExists:
+		return false, fmt.Errorf("table %s already exists", tableName)
+	case filehandler.AppendIfExists:
+		return true, nil
+	}
+
+	if c.mode == filehandler.UpsertMode {
+		return true, nil
+	}
+
+	return true, c.storage.DropTable(tableName)
+}
+
+// importOptions options changing the imported content, a file imported with other options is imported again
+func (c *csvHandler) importOptions() string {
+	return fmt.Sprintf("delimiter=%q lines=%d select=%q where=%q sample=%d/%g sample-by=%q seed=%d", c.delimiter, c.limitLines,
+		strings.Join(c.selectCols, ","), c.where, c.sample.Rows, c.sample.Percent, c.sampleBy, c.seed)
+}
+
+// loadDataFromFile load data from file
+func (c *csvHandler) loadDataFromFile(tableName string, file *os.File) error {
 	c.bar.ChangeMax(c.totalLines)
 
 	r := csv.NewReader(file)
 	r.Comma = c.delimiter
 
-	columns, err := c.readHeader(tableName, r)
+	sel, err := c.readHeader(tableName, r)
 	if err != nil {
 		return fmt.Errorf("failed to load headers and build structure: %w", err)
 	}
 
+	var before, changed, unchanged int64
+	if c.mode == filehandler.UpsertMode {
+		if before, err = c.countRows(tableName); err != nil {
+			return err
+		}
+	}
+
+	c.sampler = nil
+	if c.sample.Enabled() {
+		c.sampler = filehandler.NewSampler(c.sample, c.seed)
+	}
+
+	batchRows := 1
+	if (c.where != "" || c.rowsInserter() != nil) && len(sel.header) < batchValues {
+		batchRows = batchValues / len(sel.header)
+	}
+
 	c.currentLine = 0
+	batch := make([][]string, 0, batchRows)
 	for {
-		err := c.readline(tableName, columns, r)
+		records, err := c.readline(r)
 		if errors.Is(err, io.EOF) {
 			break
 		}
@@ -146,46 +415,372 @@ func (c *csvHandler) loadDataFromFile(tableName string, file *os.File) error {
 		if err != nil {
 			return err
 		}
+
+		if batch = append(batch, records); len(batch) < batchRows {
+			continue
+		}
+
+		if err := c.storeBatch(tableName, sel, batch, &changed, &unchanged); err != nil {
+			return err
+		}
+
+		batch = batch[:0]
+	}
+
+	if err := c.storeBatch(tableName, sel, batch, &changed, &unchanged); err != nil {
+		return err
+	}
+
+	if inserter := c.rowsInserter(); c.sampler != nil && inserter != nil {
+		sampled := c.sampler.Rows()
+		for len(sampled) > 0 {
+			n := batchRows
+			if n > len(sampled) {
+				n = len(sampled)
+			}
+
+			stored, err := c.insertRows(inserter, tableName, sel, sampled[:n])
+			if err != nil {
+				return fmt.Errorf("failed to process sampled rows: %w", err)
+			}
+
+			changed += stored
+			unchanged += int64(n) - stored
+			sampled = sampled[n:]
+		}
+	} else if c.sampler != nil {
+		for _, records := range c.sampler.Rows() {
+			ok, err := c.storeRow(tableName, sel, records)
+			if err != nil {
+				return fmt.Errorf("failed to process sampled row: %w", err)
+			}
+
+			if ok {
+				changed++
+			} else {
+				unchanged++
+			}
+		}
+	}
+
+	if err := c.createAutoIndexes(tableName, sel.columns); err != nil {
+		return err
+	}
+
+	if c.mode != filehandler.UpsertMode {
+		return nil
+	}
+
+	after, err := c.countRows(tableName)
+	if err != nil {
+		return err
+	}
+
+	c.summaries = append(c.summaries, filehandler.Summary{
+		Table:     tableName,
+		Inserted:  after - before,
+		Updated:   changed - (after - before),
+		Unchanged: unchanged,
+	})
+
+	return nil
+}
+
+// createAutoIndexes index the columns that look like identifiers or keys, except single column primary keys
+func (c *csvHandler) createAutoIndexes(tableName string, columns []string) error {
+	if !c.autoIndex {
+		return nil
+	}
+
+	for _, col := range columns {
+		name := strings.Trim(col, "`")
+		if !filehandler.IsKeyColumn(name) || (len(c.primaryKey) == 1 && c.primaryKey[0] == name) {
+			continue
+		}
+
+		if err := c.storage.CreateIndex(tableName, []string{name}, false); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// readHeader read header
-func (c *csvHandler) readHeader(tableName string, r *csv.Reader) ([]string, error) {
-	columns, err := r.Read()
+// countRows count rows of the table
+func (c *csvHandler) countRows(tableName string) (int64, error) {
+	rows, err := c.storage.Query(fmt.Sprintf(sqlCountTemplate, tableName))
 	if err != nil {
-		return nil, fmt.Errorf("failed to load headers: %w", err)
+		return 0, fmt.Errorf("failed to count rows of table %s: %w", tableName, err)
+	}
+	defer func(rows storage.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	var total int64
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return 0, fmt.Errorf("failed to count rows of table %s: %w", tableName, err)
+		}
+
+		total, _ = values[0].(int64)
+	}
+
+	return total, rows.Err()
+}
+
+// readHeader read header and build the table with the selected columns
+func (c *csvHandler) readHeader(tableName string, r *csv.Reader) (selection, error) {
+	header, err := r.Read()
+	if err != nil {
+		return selection{}, fmt.Errorf("failed to load headers: %w", err)
+	}
+
+	sel, err := c.selectColumns(header)
+	if err != nil {
+		return selection{}, err
+	}
+
+	sel.stratum = -1
+	if c.sampleBy != "" {
+		for i, h := range header {
+			if h == c.sampleBy {
+				sel.stratum = i
+				break
+			}
+		}
+
+		if sel.stratum < 0 {
+			return selection{}, fmt.Errorf("sample-by column %s not found", c.sampleBy)
+		}
+	}
+
+	if err := c.storage.BuildStructure(tableName, sel.columns); err != nil {
+		return selection{}, fmt.Errorf("failed to load headers and build structure: %w", err)
+	}
+
+	if err := c.createPrimaryKey(tableName, sel.columns); err != nil {
+		return selection{}, err
+	}
+
+	return sel, nil
+}
+
+// createPrimaryKey create unique index on the primary key columns
+func (c *csvHandler) createPrimaryKey(tableName string, columns []string) error {
+	if len(c.primaryKey) == 0 {
+		return nil
+	}
+
+	for _, key := range c.primaryKey {
+		if !c.hasColumn(columns, key) {
+			return fmt.Errorf("primary key column %s not found", key)
+		}
+	}
+
+	if err := c.storage.CreateIndex(tableName, c.primaryKey, true); err != nil {
+		return fmt.Errorf("failed to create primary key: %w", err)
+	}
+
+	return nil
+}
+
+// selectColumns columns of the header loaded into the table, all of them when none is selected
+func (c *csvHandler) selectColumns(header []string) (selection, error) {
+	sel := selection{header: header}
+	if len(c.selectCols) == 0 {
+		for i, col := range header {
+			sel.columns = append(sel.columns, col)
+			sel.positions = append(sel.positions, i)
+		}
+
+		return sel, nil
 	}
 
-	if err := c.storage.BuildStructure(tableName, columns); err != nil {
-		return nil, fmt.Errorf("failed to load headers and build structure: %w", err)
+	for _, col := range c.selectCols {
+		position := -1
+		for i, h := range header {
+			if h == col {
+				position = i
+				break
+			}
+		}
+
+		if position < 0 {
+			return selection{}, fmt.Errorf("select column %s not found", col)
+		}
+
+		sel.columns = append(sel.columns, col)
+		sel.positions = append(sel.positions, position)
+	}
+
+	return sel, nil
+}
+
+// stratumOf value of the sample-by column, empty when rows are not stratified
+func (s selection) stratumOf(records []string) string {
+	if s.stratum < 0 {
+		return ""
+	}
+
+	return records[s.stratum]
+}
+
+// project values of the selected columns
+func (s selection) project(records []string) []any {
+	values := make([]any, 0, len(s.positions))
+	for _, i := range s.positions {
+		values = append(values, records[i])
+	}
+
+	return values
+}
+
+// hasColumn check if column is in the header, ignoring quotes
+func (c *csvHandler) hasColumn(columns []string, column string) bool {
+	for _, col := range columns {
+		if strings.Trim(col, "`") == column {
+			return true
+		}
 	}
 
-	return columns, nil
+	return false
 }
 
-// readline read line
-func (c *csvHandler) readline(tableName string, columns []string, r *csv.Reader) error {
+// readline read the next row of the file, io.EOF when the file or the lines limit ends
+func (c *csvHandler) readline(r *csv.Reader) ([]string, error) {
+	if c.isCanceled() {
+		return nil, filehandler.ErrImportCanceled
+	}
+
 	records, err := r.Read()
 	if err != nil {
-		return fmt.Errorf("failed to read line: %w", err)
+		return nil, fmt.Errorf("failed to read line: %w", err)
 	}
 
 	if c.totalLines == c.currentLine {
-		return io.EOF
+		return nil, io.EOF
 	}
 
 	_ = c.bar.Add(1)
 	c.currentLine++
 
-	if err := c.storage.InsertRow(tableName, columns, c.convertToAnyArray(records)); err != nil {
-		return fmt.Errorf("failed to process row number %d: %w", c.currentLine, err)
+	return records, nil
+}
+
+// storeBatch store the rows of the batch matching where and kept by the sampler, counting the rows changed and
+// unchanged by upsert, rows are stored at once when the storage supports it
+func (c *csvHandler) storeBatch(tableName string, sel selection, batch [][]string, changed *int64, unchanged *int64) error {
+	matches, err := c.matchWhere(sel, batch)
+	if err != nil {
+		return err
+	}
+
+	inserter := c.rowsInserter()
+	kept := make([][]string, 0, len(batch))
+	first := c.currentLine - len(batch) + 1
+	for i, records := range batch {
+		if !matches[i] || (c.sampler != nil && !c.sampler.Keep(sel.stratumOf(records), records)) {
+			continue
+		}
+
+		if inserter != nil {
+			kept = append(kept, records)
+			continue
+		}
+
+		ok, err := c.storeRow(tableName, sel, records)
+		if err != nil {
+			return fmt.Errorf("failed to process row number %d: %w", first+i, err)
+		}
+
+		if ok {
+			*changed++
+		} else {
+			*unchanged++
+		}
 	}
 
+	if len(kept) == 0 {
+		return nil
+	}
+
+	stored, err := c.insertRows(inserter, tableName, sel, kept)
+	if err != nil {
+		return fmt.Errorf("failed to process rows up to number %d: %w", c.currentLine, err)
+	}
+
+	*changed += stored
+	*unchanged += int64(len(kept)) - stored
+
 	return nil
 }
 
+// rowsInserter storage storing many rows at once, nil when the storage stores them one by one
+func (c *csvHandler) rowsInserter() storage.RowsInserter {
+	inserter, ok := c.storage.(storage.RowsInserter)
+	if !ok {
+		return nil
+	}
+
+	return inserter
+}
+
+// insertRows insert or upsert the selected columns of the rows at once, returning how many rows changed
+func (c *csvHandler) insertRows(inserter storage.RowsInserter, tableName string, sel selection, rows [][]string) (int64, error) {
+	values := make([][]any, len(rows))
+	for i, records := range rows {
+		values[i] = sel.project(records)
+	}
+
+	if c.mode == filehandler.UpsertMode {
+		return inserter.UpsertRows(tableName, sel.columns, c.primaryKey, values)
+	}
+
+	if err := inserter.InsertRows(tableName, sel.columns, values); err != nil {
+		return 0, err
+	}
+
+	return int64(len(rows)), nil
+}
+
+// matchWhere check which rows of the batch match where with a single query, all of them when there is no where
+func (c *csvHandler) matchWhere(sel selection, batch [][]string) ([]bool, error) {
+	if c.where == "" {
+		matches := make([]bool, len(batch))
+		for i := range matches {
+			matches[i] = true
+		}
+
+		return matches, nil
+	}
+
+	rows := make([][]any, len(batch))
+	for i, records := range batch {
+		rows[i] = c.convertToAnyArray(records)
+	}
+
+	matches, err := c.storage.MatchRows(sel.header, rows, c.where)
+	if err != nil {
+		return nil, fmt.Errorf("failed to process rows up to number %d: %w", c.currentLine, err)
+	}
+
+	return matches, nil
+}
+
+// storeRow insert or upsert the selected values of the row, changed is false when upsert found an equal row
+func (c *csvHandler) storeRow(tableName string, sel selection, records []string) (bool, error) {
+	if c.mode == filehandler.UpsertMode {
+		return c.storage.UpsertRow(tableName, sel.columns, c.primaryKey, sel.project(records))
+	}
+
+	if err := c.storage.InsertRow(tableName, sel.columns, sel.project(records)); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
 // convertToAnyArray convert string array to any array
 func (c *csvHandler) convertToAnyArray(records []string) []any {
 	values := make([]any, 0, len(records))
@@ -244,6 +839,9 @@ func (c *csvHandler) loadTotalRows(file string) error {
 		c.totalLines += bytes.Count(buf[:r], lineSep)
 
 		switch {
+		case c.isCanceled():
+			return filehandler.ErrImportCanceled
+
 		case err == io.EOF:
 			return nil
 
@@ -0,0 +1,359 @@
+package csv_test
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/filehandler"
+	"adrianolaselva.github.io/csvql/pkg/filehandler/csv"
+	"adrianolaselva.github.io/csvql/pkg/storage"
+	"adrianolaselva.github.io/csvql/pkg/storage/sqlite"
+	"context"
+	"fmt"
+	"github.com/schollz/progressbar/v3"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShouldSkipImportOfUnchangedFilesWithSuccess(t *testing.T) {
+	dir := t.TempDir()
+	file := writeOrders(t, dir, "id,amount\n1,10\n2,20\n")
+	datasource := filepath.Join(dir, "orders.db")
+
+	tests := []struct {
+		content string
+		touch   bool
+		drop    bool
+		refresh bool
+		skipped bool
+		expects int
+	}{
+		{expects: 2},
+		{skipped: true, expects: 2},
+		{touch: true, skipped: true, expects: 2},
+		{refresh: true, expects: 2},
+		{content: "id,amount\n1,10\n2,20\n3,30\n", expects: 3},
+		{drop: true, expects: 3},
+		{skipped: true, expects: 3},
+	}
+
+	for i, test := range tests {
+		if test.content != "" {
+			writeOrders(t, dir, test.content)
+		}
+
+		if test.drop {
+			s, err := sqlite.NewSqLiteStorage(datasource)
+			assert.NoError(t, err)
+			_, err = s.ExecContext(context.Background(), "drop table orders;")
+			assert.NoError(t, err)
+			assert.NoError(t, s.Close())
+		}
+
+		if test.touch {
+			modified := time.Now().Add(time.Hour)
+			assert.NoError(t, os.Chtimes(file, modified, modified))
+		}
+
+		handler, s, err := importOrders(t, file, datasource, filehandler.Options{Refresh: test.refresh, Persistent: true})
+		assert.NoError(t, err)
+
+		// a row added after each import is only kept while the file is skipped
+		skipped := 0
+		var summaries []filehandler.Summary
+		if test.skipped {
+			skipped = 1
+			summaries = []filehandler.Summary{{Table: "orders", Path: file, Skipped: true}}
+		}
+
+		assert.Equal(t, summaries, handler.Summaries(), i)
+
+		assert.Equal(t, int64(test.expects+skipped), countRows(t, s, "select count(*) from orders;"), i)
+		_, err = s.ExecContext(context.Background(), "delete from orders where id = 'added';")
+		assert.NoError(t, err)
+		_, err = s.ExecContext(context.Background(), "insert into orders values ('added', '0');")
+		assert.NoError(t, err)
+
+		assert.NoError(t, handler.Close())
+	}
+}
+
+func TestShouldAppendOnlyChangedFilesWithSuccess(t *testing.T) {
+	dir := t.TempDir()
+	datasource := filepath.Join(dir, "orders.db")
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "2023"), 0o755))
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "2024"), 0o755))
+	first := writeOrders(t, filepath.Join(dir, "2023"), "id,amount\n1,10\n2,20\n")
+	second := writeOrders(t, filepath.Join(dir, "2024"), "id,amount\n3,30\n4,40\n")
+
+	tests := []struct {
+		content string
+		skipped []string
+		expects int64
+	}{
+		{expects: 4},
+		{skipped: []string{first, second}, expects: 4},
+		{content: "id,amount\n3,30\n4,40\n5,50\n", skipped: []string{first}, expects: 7},
+	}
+
+	for i, test := range tests {
+		if test.content != "" {
+			writeOrders(t, filepath.Join(dir, "2024"), test.content)
+		}
+
+		s, err := sqlite.NewSqLiteStorage(datasource)
+		assert.NoError(t, err)
+
+		bar := progressbar.NewOptions(0, progressbar.OptionSetWriter(io.Discard))
+		handler := csv.NewCsvHandler([]string{first, second}, ',', bar, s, filehandler.Options{
+			IfExists:   filehandler.AppendIfExists,
+			Persistent: true,
+		})
+		assert.NoError(t, handler.Import())
+
+		var summaries []filehandler.Summary
+		for _, path := range test.skipped {
+			summaries = append(summaries, filehandler.Summary{Table: "orders", Path: path, Skipped: true})
+		}
+
+		assert.Equal(t, summaries, handler.Summaries(), i)
+		assert.Equal(t, test.expects, countRows(t, s, "select count(*) from orders;"), i)
+
+		assert.NoError(t, handler.Close())
+	}
+}
+
+func TestShouldNotRecordImportsInMemoryWithSuccess(t *testing.T) {
+	file := writeOrders(t, t.TempDir(), "id,amount\n1,10\n2,20\n")
+
+	handler, s, err := importOrders(t, file, "", filehandler.Options{})
+	assert.NoError(t, err)
+
+	_, ok, err := s.LoadFingerprint("orders", file)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, handler.Close())
+}
+
+func TestShouldImportIntoExistingTablesWithSuccess(t *testing.T) {
+	tests := []struct {
+		ifExists string
+		expects  int64
+		err      bool
+	}{
+		{ifExists: filehandler.ReplaceIfExists, expects: 2},
+		{ifExists: filehandler.AppendIfExists, expects: 4},
+		{ifExists: filehandler.SkipIfExists, expects: 2},
+		{ifExists: filehandler.FailIfExists, expects: 2, err: true},
+	}
+
+	for _, test := range tests {
+		dir := t.TempDir()
+		file := writeOrders(t, dir, "id,amount\n1,10\n2,20\n")
+		datasource := filepath.Join(dir, "orders.db")
+
+		for i, opts := range []filehandler.Options{{}, {Refresh: true, IfExists: test.ifExists}} {
+			handler, _, err := importOrders(t, file, datasource, opts)
+			if i > 0 && test.err {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+
+			assert.NoError(t, handler.Close())
+		}
+
+		s, err := sqlite.NewSqLiteStorage(datasource)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expects, countRows(t, s, "select count(*) from orders;"), test.ifExists)
+		assert.NoError(t, s.Close())
+	}
+}
+
+func TestShouldUpsertRowsWithSuccess(t *testing.T) {
+	dir := t.TempDir()
+	datasource := filepath.Join(dir, "orders.db")
+
+	tests := []struct {
+		content string
+		expects filehandler.Summary
+	}{
+		{
+			content: "id,amount\n1,10\n2,20\n",
+			expects: filehandler.Summary{Table: "orders", Inserted: 2},
+		},
+		{
+			content: "id,amount\n1,10\n2,25\n3,30\n",
+			expects: filehandler.Summary{Table: "orders", Inserted: 1, Updated: 1, Unchanged: 1},
+		},
+	}
+
+	for _, test := range tests {
+		file := writeOrders(t, dir, test.content)
+
+		handler, _, err := importOrders(t, file, datasource, filehandler.Options{
+			PrimaryKey: []string{"id"},
+			Mode:       filehandler.UpsertMode,
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, []filehandler.Summary{test.expects}, handler.Summaries())
+
+		assert.NoError(t, handler.Close())
+	}
+}
+
+func TestShouldSelectAndFilterRowsWithSuccess(t *testing.T) {
+	file := writeOrders(t, t.TempDir(), "id,amount,day\n1,10,2023-01-03\n2,20,2023-02-01\n3,30,2023-02-15\n")
+
+	handler, s, err := importOrders(t, file, "", filehandler.Options{
+		Select: []string{"day", "id"},
+		Where:  "day >= '2023-02-01' and cast(amount as integer) < 30",
+	})
+	assert.NoError(t, err)
+
+	rows, err := s.Query("select * from orders;")
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"day", "id"}, storage.ColumnNames(rows.Columns()))
+
+	assert.True(t, rows.Next())
+	values, err := rows.Values()
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"2023-02-01", "2"}, values)
+	assert.False(t, rows.Next())
+	assert.NoError(t, rows.Close())
+
+	assert.NoError(t, handler.Close())
+}
+
+func TestShouldFilterRowsInBatchesWithSuccess(t *testing.T) {
+	var content strings.Builder
+	content.WriteString("id,amount\n")
+	for i := 1; i <= 12001; i++ {
+		content.WriteString(fmt.Sprintf("%d,%d\n", i, i%3))
+	}
+	file := writeOrders(t, t.TempDir(), content.String())
+
+	handler, s, err := importOrders(t, file, "", filehandler.Options{
+		Select: []string{"id"},
+		Where:  "amount = '0' or id = '12001'",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, int64(4001), countRows(t, s, "select count(*) from orders;"))
+	assert.Equal(t, int64(1), countRows(t, s, "select count(*) from orders where id = '12001';"))
+	assert.Equal(t, int64(0), countRows(t, s, "select count(*) from orders where cast(id as integer) % 3 != 0 and id != '12001';"))
+
+	assert.NoError(t, handler.Close())
+}
+
+func TestShouldSampleRowsWithSuccess(t *testing.T) {
+	var content strings.Builder
+	content.WriteString("id,region\n")
+	for i := 1; i <= 10000; i++ {
+		region := "north"
+		if i%10 == 0 {
+			region = "south"
+		}
+		content.WriteString(fmt.Sprintf("%d,%s\n", i, region))
+	}
+	file := writeOrders(t, t.TempDir(), content.String())
+
+	sampleIDs := func(opts filehandler.Options) ([]string, map[string]int64) {
+		handler, s, err := importOrders(t, file, "", opts)
+		assert.NoError(t, err)
+		defer func(handler filehandler.FileHandler) {
+			_ = handler.Close()
+		}(handler)
+
+		rows, err := s.Query("select id, region from orders order by cast(id as integer);")
+		assert.NoError(t, err)
+		defer func(rows storage.Rows) {
+			_ = rows.Close()
+		}(rows)
+
+		ids := make([]string, 0)
+		strata := make(map[string]int64)
+		for rows.Next() {
+			values, err := rows.Values()
+			assert.NoError(t, err)
+			ids = append(ids, values[0].(string))
+			strata[values[1].(string)]++
+		}
+
+		return ids, strata
+	}
+
+	ids, strata := sampleIDs(filehandler.Options{Sample: filehandler.Sample{Rows: 5}, SampleBy: "region", Seed: 42})
+	assert.Equal(t, map[string]int64{"north": 5, "south": 5}, strata)
+
+	again, _ := sampleIDs(filehandler.Options{Sample: filehandler.Sample{Rows: 5}, SampleBy: "region", Seed: 42})
+	assert.Equal(t, ids, again)
+
+	other, _ := sampleIDs(filehandler.Options{Sample: filehandler.Sample{Rows: 5}, SampleBy: "region", Seed: 7})
+	assert.NotEqual(t, ids, other)
+
+	ids, strata = sampleIDs(filehandler.Options{Sample: filehandler.Sample{Percent: 20}, Seed: 42})
+	assert.InDelta(t, 2000, len(ids), 200)
+	assert.InDelta(t, 0.1, float64(strata["south"])/float64(len(ids)), 0.02)
+	assert.InDelta(t, 0.9, float64(strata["north"])/float64(len(ids)), 0.02)
+
+	again, _ = sampleIDs(filehandler.Options{Sample: filehandler.Sample{Percent: 20}, Seed: 42})
+	assert.Equal(t, ids, again)
+}
+
+func TestShouldCancelImportWithSuccess(t *testing.T) {
+	file := writeOrders(t, t.TempDir(), "id,amount\n1,10\n2,20\n")
+
+	s, err := sqlite.NewSqLiteStorage("")
+	assert.NoError(t, err)
+
+	bar := progressbar.NewOptions(0, progressbar.OptionSetWriter(io.Discard))
+	handler := csv.NewCsvHandler([]string{file}, ',', bar, s, filehandler.Options{})
+	handler.Cancel()
+	assert.ErrorIs(t, handler.Import(), filehandler.ErrImportCanceled)
+
+	exists, err := s.HasTable("orders")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.NoError(t, handler.Close())
+}
+
+// writeOrders write the orders.csv file in dir, returning its path
+func writeOrders(t *testing.T, dir string, content string) string {
+	file := filepath.Join(dir, "orders.csv")
+	assert.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+
+	return file
+}
+
+// importOrders import file into a sqlite storage, in memory when datasource is empty, closing the handler closes
+// the storage
+func importOrders(t *testing.T, file string, datasource string, opts filehandler.Options) (filehandler.FileHandler, storage.Storage, error) {
+	s, err := sqlite.NewSqLiteStorage(datasource)
+	assert.NoError(t, err)
+
+	bar := progressbar.NewOptions(0, progressbar.OptionSetWriter(io.Discard))
+	handler := csv.NewCsvHandler([]string{file}, ',', bar, s, opts)
+
+	return handler, s, handler.Import()
+}
+
+// countRows value of the count query
+func countRows(t *testing.T, s storage.Storage, query string) int64 {
+	rows, err := s.Query(query)
+	assert.NoError(t, err)
+	defer func(rows storage.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	assert.True(t, rows.Next())
+	values, err := rows.Values()
+	assert.NoError(t, err)
+
+	return values[0].(int64)
+}
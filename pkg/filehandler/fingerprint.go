@@ -0,0 +1,65 @@
+package filehandler
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/storage"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Fingerprint identify file by path, size and modification time, the hash is loaded apart by Hash
+func Fingerprint(path string, options string) (storage.Fingerprint, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return storage.Fingerprint{}, fmt.Errorf("failed to stat file %s: %w", path, err)
+	}
+
+	return storage.Fingerprint{
+		Path:    path,
+		Size:    info.Size(),
+		ModTime: info.ModTime().UnixNano(),
+		Options: options,
+	}, nil
+}
+
+// Hash sha256 of the file content
+func Hash(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open file %s: %w", path, err)
+	}
+	defer func(f *os.File) {
+		_ = f.Close()
+	}(f)
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash file %s: %w", path, err)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Unchanged check if the file still matches the fingerprint recorded on import, the hash is only compared
+// when the modification time changed and is filled in current when loaded
+func Unchanged(recorded storage.Fingerprint, current *storage.Fingerprint) (bool, error) {
+	if recorded.Path != current.Path || recorded.Size != current.Size || recorded.Options != current.Options {
+		return false, nil
+	}
+
+	if recorded.ModTime == current.ModTime {
+		current.Hash = recorded.Hash
+		return true, nil
+	}
+
+	hash, err := Hash(current.Path)
+	if err != nil {
+		return false, err
+	}
+
+	current.Hash = hash
+
+	return hash == recorded.Hash, nil
+}
@@ -0,0 +1,52 @@
+package filehandler
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Index columns indexed in a table
+type Index struct {
+	Table   string
+	Columns []string
+}
+
+// ParseIndex parse index in the `table(col1,col2)` format
+func ParseIndex(spec string) (Index, error) {
+	spec = strings.TrimSpace(spec)
+	open := strings.Index(spec, "(")
+	if open <= 0 || !strings.HasSuffix(spec, ")") {
+		return Index{}, fmt.Errorf("invalid index %s, expected table(col1,col2)", spec)
+	}
+
+	index := Index{Table: strings.TrimSpace(spec[:open])}
+	for _, col := range strings.Split(spec[open+1:len(spec)-1], ",") {
+		if col = strings.TrimSpace(col); col != "" {
+			index.Columns = append(index.Columns, col)
+		}
+	}
+
+	if len(index.Columns) == 0 {
+		return Index{}, fmt.Errorf("invalid index %s, expected table(col1,col2)", spec)
+	}
+
+	return index, nil
+}
+
+// IsKeyColumn check if the column name looks like an identifier or key, such as `id`, `customer_id`,
+// `customerId` or `order_key`
+func IsKeyColumn(name string) bool {
+	lower := strings.ToLower(name)
+	switch {
+	case lower == "id" || lower == "key" || lower == "uuid":
+		return true
+	case strings.HasSuffix(lower, "_id") || strings.HasSuffix(lower, "_key") || strings.HasSuffix(lower, "_uuid"):
+		return true
+	}
+
+	runes := []rune(name)
+	n := len(runes)
+
+	return n > 2 && runes[n-2] == 'I' && runes[n-1] == 'd' && unicode.IsLower(runes[n-3])
+}
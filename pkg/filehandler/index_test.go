@@ -0,0 +1,42 @@
+package filehandler_test
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/filehandler"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestShouldParseIndexWithSuccess(t *testing.T) {
+	tests := []struct {
+		spec    string
+		expects filehandler.Index
+		err     bool
+	}{
+		{spec: "orders(customer_id)", expects: filehandler.Index{Table: "orders", Columns: []string{"customer_id"}}},
+		{spec: " orders ( customer_id , order_date ) ", expects: filehandler.Index{Table: "orders", Columns: []string{"customer_id", "order_date"}}},
+		{spec: "orders", err: true},
+		{spec: "(customer_id)", err: true},
+		{spec: "orders()", err: true},
+	}
+
+	for _, test := range tests {
+		index, err := filehandler.ParseIndex(test.spec)
+		if test.err {
+			assert.Error(t, err, test.spec)
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, test.expects, index)
+	}
+}
+
+func TestShouldDetectKeyColumnsWithSuccess(t *testing.T) {
+	for _, name := range []string{"id", "ID", "customer_id", "customerId", "order_key", "uuid"} {
+		assert.True(t, filehandler.IsKeyColumn(name), name)
+	}
+
+	for _, name := range []string{"amount", "paid", "valid", "Id2", "grid", "keyword"} {
+		assert.False(t, filehandler.IsKeyColumn(name), name)
+	}
+}
@@ -0,0 +1,121 @@
+package filehandler
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Sample number or percentage of rows sampled from each file, zero values sample nothing
+type Sample struct {
+	Rows    int
+	Percent float64
+}
+
+// ParseSample parse sample in the `N` or `pct%` format
+func ParseSample(spec string) (Sample, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.HasSuffix(spec, "%") {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(strings.TrimSuffix(spec, "%")), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return Sample{}, fmt.Errorf("invalid sample %s, expected rows N or percentage pct%%", spec)
+		}
+
+		return Sample{Percent: percent}, nil
+	}
+
+	rows, err := strconv.Atoi(spec)
+	if err != nil || rows <= 0 {
+		return Sample{}, fmt.Errorf("invalid sample %s, expected rows N or percentage pct%%", spec)
+	}
+
+	return Sample{Rows: rows}, nil
+}
+
+// Enabled check if rows are sampled
+func (s Sample) Enabled() bool {
+	return s.Rows > 0 || s.Percent > 0
+}
+
+// Sampler random sample of rows, a percentage keeps each row with that probability while a number of rows keeps
+// a reservoir of that size for each stratum until the end of the file
+type Sampler struct {
+	sample     Sample
+	rng        *rand.Rand
+	reservoirs map[string]*reservoir
+	read       int64
+}
+
+// reservoir rows sampled from a stratum
+type reservoir struct {
+	seen int64
+	rows []sampledRow
+}
+
+// sampledRow row kept by a reservoir with its position in the file
+type sampledRow struct {
+	position int64
+	values   []string
+}
+
+// NewSampler create sampler, a zero seed picks a random one
+func NewSampler(sample Sample, seed int64) *Sampler {
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+
+	return &Sampler{
+		sample:     sample,
+		rng:        rand.New(rand.NewSource(seed)),
+		reservoirs: make(map[string]*reservoir),
+	}
+}
+
+// Keep check if the row is kept right away, rows sampled by number are held until Rows instead
+func (s *Sampler) Keep(stratum string, values []string) bool {
+	s.read++
+	if s.sample.Percent > 0 {
+		return s.rng.Float64()*100 < s.sample.Percent
+	}
+
+	r, ok := s.reservoirs[stratum]
+	if !ok {
+		r = &reservoir{}
+		s.reservoirs[stratum] = r
+	}
+
+	r.seen++
+	row := sampledRow{position: s.read, values: values}
+	if len(r.rows) < s.sample.Rows {
+		r.rows = append(r.rows, row)
+		return false
+	}
+
+	if j := s.rng.Int63n(r.seen); j < int64(s.sample.Rows) {
+		r.rows[j] = row
+	}
+
+	return false
+}
+
+// Rows rows held by the reservoirs, in the order they were read
+func (s *Sampler) Rows() [][]string {
+	sampled := make([]sampledRow, 0)
+	for _, r := range s.reservoirs {
+		sampled = append(sampled, r.rows...)
+	}
+
+	sort.Slice(sampled, func(i, j int) bool {
+		return sampled[i].position < sampled[j].position
+	})
+
+	rows := make([][]string, len(sampled))
+	for i, row := range sampled {
+		rows[i] = row.values
+	}
+
+	return rows
+}
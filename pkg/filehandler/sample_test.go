@@ -0,0 +1,70 @@
+package filehandler_test
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/filehandler"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestShouldParseSampleWithSuccess(t *testing.T) {
+	tests := []struct {
+		spec    string
+		expects filehandler.Sample
+		err     bool
+	}{
+		{spec: "1000", expects: filehandler.Sample{Rows: 1000}},
+		{spec: " 2.5% ", expects: filehandler.Sample{Percent: 2.5}},
+		{spec: "100%", expects: filehandler.Sample{Percent: 100}},
+		{spec: "0", err: true},
+		{spec: "101%", err: true},
+		{spec: "abc", err: true},
+	}
+
+	for _, test := range tests {
+		sample, err := filehandler.ParseSample(test.spec)
+		if test.err {
+			assert.Error(t, err, test.spec)
+			continue
+		}
+
+		assert.NoError(t, err)
+		assert.Equal(t, test.expects, sample)
+	}
+}
+
+func TestShouldSampleRowsWithSuccess(t *testing.T) {
+	sampleRows := func(sample filehandler.Sample, seed int64) ([][]string, int) {
+		sampler := filehandler.NewSampler(sample, seed)
+		kept := 0
+		for i := 0; i < 10000; i++ {
+			stratum := "a"
+			if i%100 == 0 {
+				stratum = "b"
+			}
+
+			if sampler.Keep(stratum, []string{fmt.Sprint(i), stratum}) {
+				kept++
+			}
+		}
+
+		return sampler.Rows(), kept
+	}
+
+	rows, kept := sampleRows(filehandler.Sample{Rows: 5}, 42)
+	assert.Equal(t, 0, kept)
+	assert.Len(t, rows, 10)
+
+	strata := map[string]int{}
+	for _, row := range rows {
+		strata[row[1]]++
+	}
+	assert.Equal(t, map[string]int{"a": 5, "b": 5}, strata)
+
+	again, _ := sampleRows(filehandler.Sample{Rows: 5}, 42)
+	assert.Equal(t, rows, again)
+
+	rows, kept = sampleRows(filehandler.Sample{Percent: 10}, 42)
+	assert.Empty(t, rows)
+	assert.InDelta(t, 1000, kept, 150)
+}
@@ -1,7 +1,67 @@
 package filehandler
 
+import "errors"
+
+// ErrImportCanceled returned by imports stopped by Cancel
+var ErrImportCanceled = errors.New("import canceled")
+
 type FileHandler interface {
 	Import() error
+	ImportFile(string, string) (string, error)
 	Lines() int
+	Summaries() []Summary
+	Cancel()
 	Close() error
 }
+
+// Summary rows loaded into a table by an upsert import, or the file of Path skipped as unchanged since the last import
+type Summary struct {
+	Table     string
+	Path      string
+	Skipped   bool
+	Inserted  int64
+	Updated   int64
+	Unchanged int64
+}
+
+const (
+	ReplaceIfExists = "replace"
+	AppendIfExists  = "append"
+	SkipIfExists    = "skip"
+	FailIfExists    = "fail"
+	InsertMode      = "insert"
+	UpsertMode      = "upsert"
+)
+
+// IfExistsModes behaviors available when importing into an existing table
+var IfExistsModes = []string{ReplaceIfExists, AppendIfExists, SkipIfExists, FailIfExists}
+
+// Options import settings
+type Options struct {
+	Lines      int
+	Refresh    bool
+	IfExists   string
+	PrimaryKey []string
+	Mode       string
+	Indexes    []Index
+	AutoIndex  bool
+	Lazy       bool
+	LazyStats  bool
+	Select     []string
+	Where      string
+	Sample     Sample
+	SampleBy   string
+	Seed       int64
+	Persistent bool
+}
+
+// IsIfExistsMode check if mode is supported
+func IsIfExistsMode(mode string) bool {
+	for _, m := range IfExistsModes {
+		if m == mode {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,783 @@
+//go:build duckdb
+
+package duckdb
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/storage"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	goduckdb "github.com/marcboeker/go-duckdb"
+	"math/big"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	sqlCreateTableTemplate        = "CREATE TABLE IF NOT EXISTS %s (%s\n);"
+	sqlInsertTemplate             = "INSERT INTO %s (%s) VALUES (%s);"
+	sqlCreateStagedTemplate       = "CREATE OR REPLACE TEMP TABLE \"csvql_rows\" (%s);"
+	sqlInsertStagedTemplate       = "INSERT INTO %s (%s) SELECT %s FROM \"csvql_rows\";"
+	sqlSelectStagedTemplate       = "SELECT %s FROM \"csvql_rows\""
+	sqlDropStagedTemplate         = "DROP TABLE IF EXISTS \"csvql_rows\";"
+	sqlUpsertTemplate             = "INSERT INTO %s (%s) %s ON CONFLICT (%s) DO UPDATE SET %s WHERE %s;"
+	sqlUpsertKeysTemplate         = "INSERT INTO %s (%s) %s ON CONFLICT (%s) DO NOTHING;"
+	sqlValuesTemplate             = "VALUES (%s)"
+	sqlCreateIndexTemplate        = "CREATE %sINDEX IF NOT EXISTS %s ON %s (%s);"
+	sqlCreateTableAsTemplate      = "CREATE TABLE %s AS SELECT * FROM %s;"
+	sqlInsertSelectTemplate       = "INSERT INTO %s SELECT * FROM %s;"
+	sqlCreateViewTemplate         = "CREATE VIEW %s AS SELECT * FROM %s;"
+	sqlReadCsvTemplate            = "read_csv_auto(%s, delim=%s, header=true)"
+	sqlReadParquetTemplate        = "read_parquet(%s)"
+	sqlInsertDefaultTableTemplate = "INSERT INTO \"schemas\" (\"id\", \"name\", \"columns\", \"total_columns\") SELECT (select count(1)+1 FROM \"schemas\"),?,?,? WHERE NOT EXISTS (select 1 FROM \"schemas\" WHERE \"name\" = ?);"
+	sqlListTablesTemplate         = "select \"id\", \"name\", \"columns\", \"total_columns\" from \"schemas\" order by \"id\";"
+	sqlShowSchemaTemplate         = "select \"sql\" from (select \"sql\", 1 o from duckdb_tables() where table_name = ? union all select \"sql\", 1 o from duckdb_views() where view_name = ? union all select \"sql\", 2 o from duckdb_indexes() where table_name = ?) where \"sql\" is not null order by o;"
+	sqlDescribeTableTemplate      = "select column_index - 1 cid, column_name \"name\", data_type \"type\", not is_nullable \"notnull\", column_default dflt_value, false pk from duckdb_columns() where table_name = ? order by column_index;"
+	sqlDefaultTableTemplate       = "CREATE TABLE IF NOT EXISTS \"schemas\" (\"id\" INTEGER, \"name\" VARCHAR, \"columns\" VARCHAR, \"total_columns\" INTEGER);"
+	sqlImportsTableTemplate       = "CREATE TABLE IF NOT EXISTS \"imports\" (\"name\" VARCHAR, \"path\" VARCHAR, \"size\" BIGINT, \"mod_time\" BIGINT, \"hash\" VARCHAR, \"options\" VARCHAR, primary key (\"name\", \"path\"));"
+	sqlSelectImportTemplate       = "select \"path\", \"size\", \"mod_time\", \"hash\", \"options\" from \"imports\" where \"name\" = ? and \"path\" = ?;"
+	sqlInsertImportTemplate       = "INSERT INTO \"imports\" (\"name\", \"path\", \"size\", \"mod_time\", \"hash\", \"options\") VALUES (?,?,?,?,?,?);"
+	sqlListIndexesTemplate        = "select index_name \"name\", table_name \"table\", \"sql\" from duckdb_indexes() where \"sql\" is not null order by table_name, index_name;"
+	sqlHasTableTemplate           = "select count(1) from information_schema.tables where table_name = ?;"
+	sqlIsViewTemplate             = "select count(1) from duckdb_views() where view_name = ?;"
+	sqlDropTableTemplate          = "DROP TABLE IF EXISTS %s;"
+	sqlDropViewTemplate           = "DROP VIEW IF EXISTS %s;"
+	sqlDeleteSchemaTemplate       = "DELETE FROM \"schemas\" WHERE \"name\" = ?;"
+	sqlDeleteImportTemplate       = "DELETE FROM \"imports\" WHERE \"name\" = ?;"
+	sqlDeleteFileImportTemplate   = "DELETE FROM \"imports\" WHERE \"name\" = ? AND \"path\" = ?;"
+	sqlMatchRowsTemplate          = "select \"csvql_row\" from \"csvql_rows\" where %s;"
+	stagedTable                   = "csvql_rows"
+	stagedRowColumn               = "csvql_row"
+	parquetExtension              = ".parquet"
+	uuidType                      = "UUID"
+	uuidLength                    = 16
+)
+
+type duckDbStorage struct {
+	db *sql.DB
+}
+
+// NewDuckDbStorage open duckdb database file, in memory when datasource is empty
+func NewDuckDbStorage(datasource string) (storage.Storage, error) {
+	db, err := sql.Open("duckdb", datasource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open connection with duckdb: %w", err)
+	}
+
+	return &duckDbStorage{db: db}, nil
+}
+
+// BuildStructure build table creation statement
+func (s *duckDbStorage) BuildStructure(tableName string, columns []string) error {
+	var tableAttrsRaw strings.Builder
+	for i, v := range columns {
+		tableAttrsRaw.WriteString(fmt.Sprintf("\n\t%s VARCHAR", quoteIdentifier(v)))
+		if len(columns)-1 > i {
+			tableAttrsRaw.WriteString(",")
+		}
+	}
+
+	query := fmt.Sprintf(sqlCreateTableTemplate, quoteIdentifier(tableName), tableAttrsRaw.String())
+	if _, err := s.db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create structure: %w (sql: %s)", err, query)
+	}
+
+	return s.registerTable(tableName, columns)
+}
+
+// LoadFile create table from csv or parquet file read by duckdb, appending to the table when it exists
+func (s *duckDbStorage) LoadFile(tableName string, path string, delimiter rune) ([]string, error) {
+	exists, err := s.HasTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	template := sqlCreateTableAsTemplate
+	if exists {
+		template = sqlInsertSelectTemplate
+	}
+
+	query := fmt.Sprintf(template, quoteIdentifier(tableName), readFile(path, delimiter))
+	if _, err := s.db.Exec(query); err != nil {
+		return nil, fmt.Errorf("failed to load file %s: %w", path, err)
+	}
+
+	columns, err := s.columns(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	return columns, s.registerTable(tableName, columns)
+}
+
+// CreateVirtualTable create view reading the file on each query, duckdb reads files natively so stats are not used
+func (s *duckDbStorage) CreateVirtualTable(tableName string, path string, delimiter rune, _ bool) error {
+	query := fmt.Sprintf(sqlCreateViewTemplate, quoteIdentifier(tableName), readFile(path, delimiter))
+	if _, err := s.db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create view %s: %w", tableName, err)
+	}
+
+	columns, err := s.columns(tableName)
+	if err != nil {
+		return err
+	}
+
+	return s.registerTable(tableName, columns)
+}
+
+// readFile table function reading the file, parquet by extension and csv otherwise
+func readFile(path string, delimiter rune) string {
+	if strings.EqualFold(filepath.Ext(path), parquetExtension) {
+		return fmt.Sprintf(sqlReadParquetTemplate, quoteLiteral(path))
+	}
+
+	return fmt.Sprintf(sqlReadCsvTemplate, quoteLiteral(path), quoteLiteral(string(delimiter)))
+}
+
+// columns names of the table columns
+func (s *duckDbStorage) columns(tableName string) ([]string, error) {
+	described, err := s.DescribeTable(tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, len(described))
+	for i, c := range described {
+		columns[i] = c.Name
+	}
+
+	return columns, nil
+}
+
+// registerTable add table to the schemas catalog
+func (s *duckDbStorage) registerTable(tableName string, columns []string) error {
+	if err := s.buildCatalog(); err != nil {
+		return err
+	}
+
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdentifier(c)
+	}
+
+	columnsRaw := fmt.Sprintf("[%v]", strings.Join(quoted, ","))
+	if _, err := s.db.Exec(sqlInsertDefaultTableTemplate, tableName, columnsRaw, len(columns), tableName); err != nil {
+		return fmt.Errorf("failed to execute insert: %w", err)
+	}
+
+	return nil
+}
+
+// MatchRows check which rows match the where expression with a single query, values are compared as text like
+// imported columns
+func (s *duckDbStorage) MatchRows(columns []string, rows [][]any, where string) ([]bool, error) {
+	matches := make([]bool, len(rows))
+	if len(rows) == 0 {
+		return matches, nil
+	}
+
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate where %s: %w", where, err)
+	}
+	defer func(conn *sql.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	if err := stageRows(ctx, conn, columns, rows, true); err != nil {
+		return nil, fmt.Errorf("failed to evaluate where %s: %w", where, err)
+	}
+	defer dropStagedRows(ctx, conn)
+
+	result, err := conn.QueryContext(ctx, fmt.Sprintf(sqlMatchRowsTemplate, where))
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate where %s: %w", where, err)
+	}
+	defer func(result *sql.Rows) {
+		_ = result.Close()
+	}(result)
+
+	for result.Next() {
+		var i int
+		if err := result.Scan(&i); err != nil {
+			return nil, fmt.Errorf("failed to evaluate where %s: %w", where, err)
+		}
+
+		matches[i] = true
+	}
+
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to evaluate where %s: %w", where, err)
+	}
+
+	return matches, nil
+}
+
+// InsertRow build insert create statement
+func (s *duckDbStorage) InsertRow(tableName string, columns []string, values []any) error {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdentifier(c)
+	}
+
+	paramsRaw := strings.Repeat("?, ", len(columns))
+	query := fmt.Sprintf(sqlInsertTemplate, quoteIdentifier(tableName), strings.Join(quoted, ", "), paramsRaw[:len(paramsRaw)-2])
+	if _, err := s.db.Exec(query, values...); err != nil {
+		return fmt.Errorf("failed to execute insert: %w (sql: %s)", err, query)
+	}
+
+	return nil
+}
+
+// InsertRows insert rows copied through the appender with a single statement, much faster than one statement per
+// row in duckdb
+func (s *duckDbStorage) InsertRows(tableName string, columns []string, rows [][]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to execute insert: %w", err)
+	}
+	defer func(conn *sql.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	if err := stageRows(ctx, conn, columns, rows, false); err != nil {
+		return fmt.Errorf("failed to execute insert: %w", err)
+	}
+	defer dropStagedRows(ctx, conn)
+
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdentifier(c)
+	}
+
+	query := fmt.Sprintf(sqlInsertStagedTemplate, quoteIdentifier(tableName), strings.Join(quoted, ", "), strings.Join(quoted, ", "))
+	if _, err := conn.ExecContext(ctx, query); err != nil {
+		return fmt.Errorf("failed to execute insert: %w", err)
+	}
+
+	return nil
+}
+
+// stageRows copy rows into a temporary table of the connection through the appender, values are stored as text like
+// imported columns and numbered by their position when requested
+func stageRows(ctx context.Context, conn *sql.Conn, columns []string, rows [][]any, numbered bool) error {
+	definitions := make([]string, 0, len(columns)+1)
+	if numbered {
+		definitions = append(definitions, quoteIdentifier(stagedRowColumn)+" BIGINT")
+	}
+
+	for _, c := range columns {
+		definitions = append(definitions, quoteIdentifier(c)+" VARCHAR")
+	}
+
+	if _, err := conn.ExecContext(ctx, fmt.Sprintf(sqlCreateStagedTemplate, strings.Join(definitions, ", "))); err != nil {
+		return fmt.Errorf("failed to create staging table: %w", err)
+	}
+
+	return conn.Raw(func(driverConn any) error {
+		appender, err := goduckdb.NewAppenderFromConn(driverConn.(driver.Conn), "", stagedTable)
+		if err != nil {
+			return fmt.Errorf("failed to create appender: %w", err)
+		}
+		defer func(appender *goduckdb.Appender) {
+			_ = appender.Close()
+		}(appender)
+
+		for i, values := range rows {
+			row := make([]driver.Value, 0, len(definitions))
+			if numbered {
+				row = append(row, int64(i))
+			}
+
+			for _, v := range values {
+				row = append(row, stagedValue(v))
+			}
+
+			if err := appender.AppendRow(row...); err != nil {
+				return fmt.Errorf("failed to append row %d: %w", i, err)
+			}
+		}
+
+		if err := appender.Flush(); err != nil {
+			return fmt.Errorf("failed to flush appender: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// dropStagedRows drop the temporary table filled by stageRows
+func dropStagedRows(ctx context.Context, conn *sql.Conn) {
+	_, _ = conn.ExecContext(ctx, sqlDropStagedTemplate)
+}
+
+// stagedValue text of value as stored by imports, the appender has no support for nulls or mixed types in a column
+func stagedValue(v any) string {
+	switch value := v.(type) {
+	case string:
+		return value
+	case []byte:
+		return string(value)
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+// UpsertRow insert row or update the row with the same keys when it differs in a single statement, relying on the
+// unique index of the keys, changed is false when the existing row is equal
+func (s *duckDbStorage) UpsertRow(tableName string, columns []string, keys []string, values []any) (bool, error) {
+	paramsRaw := strings.Repeat("?, ", len(columns))
+	query := upsertQuery(tableName, columns, keys, fmt.Sprintf(sqlValuesTemplate, paramsRaw[:len(paramsRaw)-2]))
+	result, err := s.db.Exec(query, values...)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute upsert: %w (sql: %s)", err, query)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to load affected rows: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+// UpsertRows upsert rows copied through the appender with a statement per run of rows with distinct keys, so a key
+// repeated in the file is applied in order like UpsertRow, changed counts the rows inserted or updated
+func (s *duckDbStorage) UpsertRows(tableName string, columns []string, keys []string, rows [][]any) (int64, error) {
+	if len(rows) == 0 {
+		return 0, nil
+	}
+
+	ctx := context.Background()
+	conn, err := s.db.Conn(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute upsert: %w", err)
+	}
+	defer func(conn *sql.Conn) {
+		_ = conn.Close()
+	}(conn)
+
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdentifier(c)
+	}
+
+	query := upsertQuery(tableName, columns, keys, fmt.Sprintf(sqlSelectStagedTemplate, strings.Join(quoted, ", ")))
+	var changed int64
+	for _, run := range distinctKeyRuns(columns, keys, rows) {
+		if err := stageRows(ctx, conn, columns, run, false); err != nil {
+			return changed, fmt.Errorf("failed to execute upsert: %w", err)
+		}
+
+		result, err := conn.ExecContext(ctx, query)
+		if err != nil {
+			dropStagedRows(ctx, conn)
+			return changed, fmt.Errorf("failed to execute upsert: %w (sql: %s)", err, query)
+		}
+
+		affected, err := result.RowsAffected()
+		if err != nil {
+			dropStagedRows(ctx, conn)
+			return changed, fmt.Errorf("failed to load affected rows: %w", err)
+		}
+
+		changed += affected
+	}
+
+	dropStagedRows(ctx, conn)
+
+	return changed, nil
+}
+
+// upsertQuery insert the rows of source or update the rows with the same keys when they differ, rows equal to the
+// existing ones are not counted as affected
+func upsertQuery(tableName string, columns []string, keys []string, source string) string {
+	isKey := make(map[string]bool)
+	quotedKeys := make([]string, len(keys))
+	for i, k := range keys {
+		isKey[strings.Trim(k, "`\"")] = true
+		quotedKeys[i] = quoteIdentifier(k)
+	}
+
+	table := quoteIdentifier(tableName)
+	quoted := make([]string, len(columns))
+	sets := make([]string, 0, len(columns))
+	changes := make([]string, 0, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdentifier(c)
+		if isKey[strings.Trim(c, "`\"")] {
+			continue
+		}
+
+		sets = append(sets, fmt.Sprintf("%s = excluded.%s", quoted[i], quoted[i]))
+		changes = append(changes, fmt.Sprintf("%s.%s IS DISTINCT FROM excluded.%s", table, quoted[i], quoted[i]))
+	}
+
+	if len(sets) == 0 {
+		return fmt.Sprintf(sqlUpsertKeysTemplate, table, strings.Join(quoted, ", "), source, strings.Join(quotedKeys, ", "))
+	}
+
+	return fmt.Sprintf(sqlUpsertTemplate, table, strings.Join(quoted, ", "), source, strings.Join(quotedKeys, ", "),
+		strings.Join(sets, ", "), strings.Join(changes, " OR "))
+}
+
+// distinctKeyRuns split rows in consecutive runs without repeated keys, duckdb refuses to update the same row twice in
+// a single statement
+func distinctKeyRuns(columns []string, keys []string, rows [][]any) [][][]any {
+	isKey := make(map[string]bool)
+	for _, k := range keys {
+		isKey[strings.Trim(k, "`\"")] = true
+	}
+
+	positions := make([]int, 0, len(keys))
+	for i, c := range columns {
+		if isKey[strings.Trim(c, "`\"")] {
+			positions = append(positions, i)
+		}
+	}
+
+	runs := make([][][]any, 0, 1)
+	seen := make(map[string]bool)
+	first := 0
+	for i, values := range rows {
+		parts := make([]string, len(positions))
+		for j, p := range positions {
+			parts[j] = stagedValue(values[p])
+		}
+
+		key := strings.Join(parts, "\x00")
+		if seen[key] {
+			runs = append(runs, rows[first:i])
+			seen = make(map[string]bool)
+			first = i
+		}
+
+		seen[key] = true
+	}
+
+	return append(runs, rows[first:])
+}
+
+// CreateIndex create index on the table columns, unique indexes are used as primary keys
+func (s *duckDbStorage) CreateIndex(tableName string, columns []string, unique bool) error {
+	quoted := make([]string, len(columns))
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdentifier(c)
+		names[i] = strings.Trim(c, "`\"")
+	}
+
+	kind, suffix := "", "idx"
+	if unique {
+		kind, suffix = "UNIQUE ", "key"
+	}
+
+	name := quoteIdentifier(fmt.Sprintf("%s_%s_%s", tableName, strings.Join(names, "_"), suffix))
+	query := fmt.Sprintf(sqlCreateIndexTemplate, kind, name, quoteIdentifier(tableName), strings.Join(quoted, ", "))
+	if _, err := s.db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create index: %w (sql: %s)", err, query)
+	}
+
+	return nil
+}
+
+// Query execute statements, binding args and params to its placeholders
+func (s *duckDbStorage) Query(cmd string, args ...any) (storage.Rows, error) {
+	return s.QueryContext(context.Background(), cmd, args...)
+}
+
+// ExecContext execute statement that returns no rows, stopping when ctx is done, returns the number of affected rows
+func (s *duckDbStorage) ExecContext(ctx context.Context, cmd string, args ...any) (int64, error) {
+	cmd, args = storage.PositionalArgs(cmd, args)
+	result, err := s.db.ExecContext(ctx, cmd, args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute statement: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load affected rows: %w", err)
+	}
+
+	return affected, nil
+}
+
+// QueryContext execute statements, stopping when ctx is done
+func (s *duckDbStorage) QueryContext(ctx context.Context, cmd string, args ...any) (storage.Rows, error) {
+	cmd, args = storage.PositionalArgs(cmd, args)
+	rows, err := s.db.QueryContext(ctx, cmd, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	return storage.NewSqlRows(rows, convertValue)
+}
+
+// ListTables list tables of the schemas catalog in the order they were created
+func (s *duckDbStorage) ListTables() ([]storage.Table, error) {
+	if err := s.buildCatalog(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(sqlListTablesTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	tables := make([]storage.Table, 0)
+	for rows.Next() {
+		var table storage.Table
+		if err := rows.Scan(&table.ID, &table.Name, &table.Columns, &table.TotalColumns); err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+
+		tables = append(tables, table)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	return tables, nil
+}
+
+// ShowSchema show statements used to create the table and its indexes
+func (s *duckDbStorage) ShowSchema(tableName string) ([]string, error) {
+	statements, err := s.queryStrings(sqlShowSchemaTemplate, tableName, tableName, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema of table %s: %w", tableName, err)
+	}
+
+	return statements, nil
+}
+
+// DescribeTable list columns of the table
+func (s *duckDbStorage) DescribeTable(tableName string) ([]storage.TableColumn, error) {
+	rows, err := s.db.Query(sqlDescribeTableTemplate, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe table %s: %w", tableName, err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	columns := make([]storage.TableColumn, 0)
+	for rows.Next() {
+		var column storage.TableColumn
+		var cid int64
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &column.Name, &column.Type, &column.NotNull, &dflt, &column.PrimaryKey); err != nil {
+			return nil, fmt.Errorf("failed to describe table %s: %w", tableName, err)
+		}
+
+		if dflt.Valid {
+			column.Default = &dflt.String
+		}
+
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+// ListIndexes list indexes created on tables
+func (s *duckDbStorage) ListIndexes() ([]storage.Index, error) {
+	rows, err := s.db.Query(sqlListIndexesTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	indexes := make([]storage.Index, 0)
+	for rows.Next() {
+		var index storage.Index
+		if err := rows.Scan(&index.Name, &index.Table, &index.SQL); err != nil {
+			return nil, fmt.Errorf("failed to list indexes: %w", err)
+		}
+
+		indexes = append(indexes, index)
+	}
+
+	return indexes, rows.Err()
+}
+
+// queryStrings values of the single column returned by the query
+func (s *duckDbStorage) queryStrings(query string, args ...any) ([]string, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	values := make([]string, 0)
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
+}
+
+// HasTable check if table or view exists
+func (s *duckDbStorage) HasTable(tableName string) (bool, error) {
+	var total int
+	if err := s.db.QueryRow(sqlHasTableTemplate, tableName).Scan(&total); err != nil {
+		return false, fmt.Errorf("failed to check table %s: %w", tableName, err)
+	}
+
+	return total > 0, nil
+}
+
+// DropTable drop table or view and remove it from the catalog
+func (s *duckDbStorage) DropTable(tableName string) error {
+	if err := s.buildCatalog(); err != nil {
+		return err
+	}
+
+	var views int
+	if err := s.db.QueryRow(sqlIsViewTemplate, tableName).Scan(&views); err != nil {
+		return fmt.Errorf("failed to check table %s: %w", tableName, err)
+	}
+
+	template := sqlDropTableTemplate
+	if views > 0 {
+		template = sqlDropViewTemplate
+	}
+
+	if _, err := s.db.Exec(fmt.Sprintf(template, quoteIdentifier(tableName))); err != nil {
+		return fmt.Errorf("failed to drop table %s: %w", tableName, err)
+	}
+
+	if _, err := s.db.Exec(sqlDeleteSchemaTemplate, tableName); err != nil {
+		return fmt.Errorf("failed to remove table %s from schemas: %w", tableName, err)
+	}
+
+	if _, err := s.db.Exec(sqlDeleteImportTemplate, tableName); err != nil {
+		return fmt.Errorf("failed to remove table %s from imports: %w", tableName, err)
+	}
+
+	return nil
+}
+
+// LoadFingerprint load fingerprint of the file imported into the table, ok is false when there is none
+func (s *duckDbStorage) LoadFingerprint(tableName string, path string) (storage.Fingerprint, bool, error) {
+	if err := s.buildCatalog(); err != nil {
+		return storage.Fingerprint{}, false, err
+	}
+
+	var fp storage.Fingerprint
+	err := s.db.QueryRow(sqlSelectImportTemplate, tableName, path).Scan(&fp.Path, &fp.Size, &fp.ModTime, &fp.Hash, &fp.Options)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Fingerprint{}, false, nil
+	}
+
+	if err != nil {
+		return storage.Fingerprint{}, false, fmt.Errorf("failed to load fingerprint of table %s: %w", tableName, err)
+	}
+
+	return fp, true, nil
+}
+
+// SaveFingerprint record fingerprint of the file imported into the table
+func (s *duckDbStorage) SaveFingerprint(tableName string, fp storage.Fingerprint) error {
+	if err := s.buildCatalog(); err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(sqlDeleteFileImportTemplate, tableName, fp.Path); err != nil {
+		return fmt.Errorf("failed to save fingerprint of table %s: %w", tableName, err)
+	}
+
+	if _, err := s.db.Exec(sqlInsertImportTemplate, tableName, fp.Path, fp.Size, fp.ModTime, fp.Hash, fp.Options); err != nil {
+		return fmt.Errorf("failed to save fingerprint of table %s: %w", tableName, err)
+	}
+
+	return nil
+}
+
+// buildCatalog create catalog tables when missing
+func (s *duckDbStorage) buildCatalog() error {
+	if _, err := s.db.Exec(sqlDefaultTableTemplate); err != nil {
+		return fmt.Errorf("failed to create tables schemas structure: %w", err)
+	}
+
+	if _, err := s.db.Exec(sqlImportsTableTemplate); err != nil {
+		return fmt.Errorf("failed to create imports structure: %w", err)
+	}
+
+	return nil
+}
+
+// Close close database
+func (s *duckDbStorage) Close() error {
+	if err := s.db.Close(); err != nil {
+		return fmt.Errorf("failed to close duckdb connection: %w", err)
+	}
+
+	return nil
+}
+
+// quoteIdentifier quote name with double quotes, replacing the backticks used by sqlite
+func quoteIdentifier(name string) string {
+	name = strings.Trim(name, "`\"")
+	return "\"" + strings.ReplaceAll(name, "\"", "\"\"") + "\""
+}
+
+// quoteLiteral quote value as sql string literal
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// convertValue convert decimals and uuids to their exact text form
+func convertValue(column storage.Column, value any) any {
+	switch v := value.(type) {
+	case goduckdb.Decimal:
+		return decimalString(v)
+	case []byte:
+		if column.Type == uuidType && len(v) == uuidLength {
+			return fmt.Sprintf("%x-%x-%x-%x-%x", v[0:4], v[4:6], v[6:8], v[8:10], v[10:16])
+		}
+	}
+
+	return value
+}
+
+// decimalString decimal with all the digits of its scale, like 1.50 for DECIMAL(10,2)
+func decimalString(d goduckdb.Decimal) string {
+	digits := new(big.Int).Abs(d.Value).String()
+	scale := int(d.Scale)
+	if scale > 0 {
+		if len(digits) <= scale {
+			digits = strings.Repeat("0", scale-len(digits)+1) + digits
+		}
+
+		digits = digits[:len(digits)-scale] + "." + digits[len(digits)-scale:]
+	}
+
+	if d.Value.Sign() < 0 {
+		return "-" + digits
+	}
+
+	return digits
+}
@@ -0,0 +1,16 @@
+//go:build !duckdb
+
+package duckdb
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/storage"
+	"errors"
+)
+
+// errDuckDbUnsupported returned when built without the duckdb tag
+var errDuckDbUnsupported = errors.New("duckdb engine requires csvql built with `-tags duckdb`")
+
+// NewDuckDbStorage not supported without the duckdb build tag
+func NewDuckDbStorage(_ string) (storage.Storage, error) {
+	return nil, errDuckDbUnsupported
+}
@@ -0,0 +1,251 @@
+//go:build duckdb
+
+package duckdb_test
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/storage"
+	"adrianolaselva.github.io/csvql/pkg/storage/duckdb"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestShouldLoadFileWithSuccess(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "orders.csv")
+	assert.NoError(t, os.WriteFile(file, []byte("id;amount;day\n1;10.5;2024-01-03\n2;20;2024-02-01\n"), 0o644))
+
+	s, err := duckdb.NewDuckDbStorage("")
+	assert.NoError(t, err)
+
+	loader, ok := s.(storage.FileLoader)
+	assert.True(t, ok)
+
+	columns, err := loader.LoadFile("orders", file, ';')
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"id", "amount", "day"}, columns)
+
+	_, err = loader.LoadFile("orders", file, ';')
+	assert.NoError(t, err)
+
+	rows, err := s.Query("select sum(amount) total, min(day) first_day from orders;")
+	assert.NoError(t, err)
+	assert.Equal(t, []storage.Column{{Name: "total", Type: "DOUBLE"}, {Name: "first_day", Type: "DATE"}}, rows.Columns())
+	assert.True(t, rows.Next())
+	values, err := rows.Values()
+	assert.NoError(t, err)
+	assert.NoError(t, rows.Close())
+	assert.Equal(t, []any{61.0, "2024-01-03"}, values)
+
+	assert.NoError(t, s.CreateVirtualTable("lazy_orders", file, ';', false))
+	exists, err := s.HasTable("lazy_orders")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.NoError(t, s.DropTable("lazy_orders"))
+	exists, err = s.HasTable("lazy_orders")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.NoError(t, s.Close())
+}
+
+func TestShouldUpsertRowsWithSuccess(t *testing.T) {
+	s, err := duckdb.NewDuckDbStorage("")
+	assert.NoError(t, err)
+
+	columns := []string{"id", "amount"}
+	assert.NoError(t, s.BuildStructure("orders", columns))
+	assert.NoError(t, s.CreateIndex("orders", []string{"id"}, true))
+
+	tests := []struct {
+		values  []any
+		changed bool
+	}{
+		{values: []any{"1", "10"}, changed: true},
+		{values: []any{"1", "10"}, changed: false},
+		{values: []any{"1", "15"}, changed: true},
+		{values: []any{"2", "20"}, changed: true},
+	}
+
+	for _, test := range tests {
+		changed, err := s.UpsertRow("orders", columns, []string{"id"}, test.values)
+		assert.NoError(t, err)
+		assert.Equal(t, test.changed, changed, test.values)
+	}
+
+	tables, err := s.ListTables()
+	assert.NoError(t, err)
+	assert.Equal(t, []storage.Table{{ID: 1, Name: "orders", Columns: `["id","amount"]`, TotalColumns: 2}}, tables)
+
+	rows, err := s.Query("select count(*) from orders;")
+	assert.NoError(t, err)
+	assert.True(t, rows.Next())
+	values, err := rows.Values()
+	assert.NoError(t, err)
+	assert.NoError(t, rows.Close())
+	assert.Equal(t, []any{int64(2)}, values)
+
+	fp := storage.Fingerprint{Path: "/tmp/orders.csv", Size: 10, ModTime: 1, Hash: "abc", Options: "x"}
+	other := storage.Fingerprint{Path: "/tmp/2024/orders.csv", Size: 20, ModTime: 2, Hash: "def", Options: "x"}
+	assert.NoError(t, s.SaveFingerprint("orders", fp))
+	assert.NoError(t, s.SaveFingerprint("orders", fp))
+	assert.NoError(t, s.SaveFingerprint("orders", other))
+
+	for _, expects := range []storage.Fingerprint{fp, other} {
+		loaded, ok, err := s.LoadFingerprint("orders", expects.Path)
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, expects, loaded)
+	}
+
+	_, ok, err := s.LoadFingerprint("orders", "/tmp/missing.csv")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, s.Close())
+}
+
+func TestShouldBindParamsWithSuccess(t *testing.T) {
+	s, err := duckdb.NewDuckDbStorage("")
+	assert.NoError(t, err)
+	defer func(s storage.Storage) {
+		_ = s.Close()
+	}(s)
+
+	assert.NoError(t, s.BuildStructure("orders", []string{"id", "region", "amount"}))
+	for _, values := range [][]any{{"1", "north", "10"}, {"2", "south", "25"}, {"3", "north", "7"}} {
+		assert.NoError(t, s.InsertRow("orders", []string{"id", "region", "amount"}, values))
+	}
+
+	rows, err := s.Query("select id, amount::INTEGER * 2 from orders where region = :region "+
+		"and amount::INTEGER >= :min::INTEGER and region = :region and @amount::INTEGER > 0 order by id;",
+		storage.Param{Name: "min", Value: "8"}, storage.Param{Name: "region", Value: "north"})
+	assert.NoError(t, err)
+	defer func(rows storage.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	assert.True(t, rows.Next())
+	values, err := rows.Values()
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"1", int64(20)}, values)
+	assert.False(t, rows.Next())
+	assert.NoError(t, rows.Err())
+}
+
+func TestShouldMatchRowsWithSuccess(t *testing.T) {
+	s, err := duckdb.NewDuckDbStorage("")
+	assert.NoError(t, err)
+	defer func(s storage.Storage) {
+		_ = s.Close()
+	}(s)
+
+	rows := [][]any{{"1", "north"}, {"2", "south"}, {"3", "north"}}
+	matches, err := s.MatchRows([]string{"id", "region"}, rows, "region = 'north' and id > '1'")
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, false, true}, matches)
+
+	_, err = s.MatchRows([]string{"id", "region"}, rows, "missing = 1")
+	assert.Error(t, err)
+}
+
+func TestShouldConvertDecimalsAndUuidsWithSuccess(t *testing.T) {
+	s, err := duckdb.NewDuckDbStorage("")
+	assert.NoError(t, err)
+	defer func(s storage.Storage) {
+		_ = s.Close()
+	}(s)
+
+	rows, err := s.Query("select 1.50::DECIMAL(10,2), -0.05::DECIMAL(4,3), 7::DECIMAL(5,0), " +
+		"(select sum(v) from (values (12345678901234567890.1234567891::DECIMAL(38,10)), (0.0000000009::DECIMAL(38,10))) t(v)), " +
+		"'0b4e5f6a-1c2d-4e3f-8a9b-0c1d2e3f4a5b'::UUID, 7::INTEGER;")
+	assert.NoError(t, err)
+	defer func(rows storage.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	assert.True(t, rows.Next())
+	values, err := rows.Values()
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"1.50", "-0.050", "7", "12345678901234567890.1234567900",
+		"0b4e5f6a-1c2d-4e3f-8a9b-0c1d2e3f4a5b", int64(7)}, values)
+	assert.False(t, rows.Next())
+	assert.NoError(t, rows.Err())
+}
+
+func TestShouldInsertRowsWithSuccess(t *testing.T) {
+	s, err := duckdb.NewDuckDbStorage("")
+	assert.NoError(t, err)
+	defer func(s storage.Storage) {
+		_ = s.Close()
+	}(s)
+
+	assert.NoError(t, s.BuildStructure("orders", []string{"id", "region", "amount"}))
+
+	inserter, ok := s.(storage.RowsInserter)
+	assert.True(t, ok)
+
+	rows := make([][]any, 0, 5000)
+	for i := 1; i <= 5000; i++ {
+		rows = append(rows, []any{fmt.Sprint(i), fmt.Sprint(i * 2)})
+	}
+
+	assert.NoError(t, inserter.InsertRows("orders", []string{"amount", "id"}, rows))
+	assert.NoError(t, inserter.InsertRows("orders", []string{"amount", "id"}, nil))
+	assert.Error(t, inserter.InsertRows("orders", []string{"missing"}, [][]any{{"1"}}))
+
+	result, err := s.Query("select count(*), min(region), max(id::BIGINT), sum(amount::INTEGER) from orders;")
+	assert.NoError(t, err)
+	assert.True(t, result.Next())
+	values, err := result.Values()
+	assert.NoError(t, err)
+	assert.NoError(t, result.Close())
+	assert.Equal(t, []any{int64(5000), nil, int64(10000), "12502500"}, values)
+}
+
+func TestShouldUpsertRowsInBatchWithSuccess(t *testing.T) {
+	s, err := duckdb.NewDuckDbStorage("")
+	assert.NoError(t, err)
+	defer func(s storage.Storage) {
+		_ = s.Close()
+	}(s)
+
+	columns := []string{"id", "amount"}
+	assert.NoError(t, s.BuildStructure("orders", columns))
+	assert.NoError(t, s.CreateIndex("orders", []string{"id"}, true))
+
+	inserter, ok := s.(storage.RowsInserter)
+	assert.True(t, ok)
+
+	tests := []struct {
+		rows    [][]any
+		changed int64
+	}{
+		{rows: [][]any{{"1", "10"}, {"2", "20"}}, changed: 2},
+		{rows: [][]any{{"1", "10"}, {"2", "25"}, {"3", "30"}}, changed: 2},
+		{rows: [][]any{{"3", "31"}, {"3", "32"}, {"4", "40"}, {"3", "32"}}, changed: 3},
+		{rows: nil, changed: 0},
+	}
+
+	for _, test := range tests {
+		changed, err := inserter.UpsertRows("orders", columns, []string{"id"}, test.rows)
+		assert.NoError(t, err)
+		assert.Equal(t, test.changed, changed, test.rows)
+	}
+
+	rows, err := s.Query("select string_agg(id || '=' || amount, ',' order by id) from orders;")
+	assert.NoError(t, err)
+	assert.True(t, rows.Next())
+	values, err := rows.Values()
+	assert.NoError(t, err)
+	assert.NoError(t, rows.Close())
+	assert.Equal(t, []any{"1=10,2=25,3=32,4=40"}, values)
+
+	assert.NoError(t, s.BuildStructure("tags", []string{"name"}))
+	assert.NoError(t, s.CreateIndex("tags", []string{"name"}, true))
+	changed, err := inserter.UpsertRows("tags", []string{"name"}, []string{"name"}, [][]any{{"a"}, {"b"}, {"a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), changed)
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"database/sql"
+	"unicode"
+)
+
+// Param value bound to the `:name` placeholders of a statement, each engine binds it in the form it supports
+type Param struct {
+	Name  string
+	Value any
+}
+
+// Placeholders names of the `:name` placeholders of the statement in the order of first use, `@name` and `$name` are
+// left to the engine
+func Placeholders(statement string) []string {
+	names := make([]string, 0)
+	seen := make(map[string]bool)
+	scanPlaceholders(statement, func(_ int, name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	})
+
+	return names
+}
+
+// IsPlaceholderName check if name is valid as placeholder
+func IsPlaceholderName(name string) bool {
+	for i, r := range name {
+		if !isPlaceholderRune(r, i == 0) {
+			return false
+		}
+	}
+
+	return name != ""
+}
+
+// NamedArgs bind params by name, for engines supporting `:name` placeholders
+func NamedArgs(args []any) []any {
+	named := make([]any, len(args))
+	for i, arg := range args {
+		if p, ok := arg.(Param); ok {
+			arg = sql.Named(p.Name, p.Value)
+		}
+
+		named[i] = arg
+	}
+
+	return named
+}
+
+// PositionalArgs rewrite `:name` placeholders of the statement as `$name` and bind params by position in the order of
+// first use, for engines binding `$name` placeholders by position, args without params are returned as they are
+func PositionalArgs(statement string, args []any) (string, []any) {
+	values := make(map[string]any)
+	for _, arg := range args {
+		if p, ok := arg.(Param); ok {
+			values[p.Name] = p.Value
+		}
+	}
+
+	if len(values) == 0 {
+		return statement, args
+	}
+
+	positional := make([]any, 0, len(values))
+	for _, name := range Placeholders(statement) {
+		if value, ok := values[name]; ok {
+			positional = append(positional, value)
+		}
+	}
+
+	return dollarPlaceholders(statement), positional
+}
+
+// dollarPlaceholders rewrite `:name` placeholders as `$name`
+func dollarPlaceholders(statement string) string {
+	runes := []rune(statement)
+	scanPlaceholders(statement, func(start int, _ string) {
+		runes[start] = '$'
+	})
+
+	return string(runes)
+}
+
+// scanPlaceholders call fn with the rune position and name of each placeholder, ignoring quotes, comments and
+// `::` casts
+func scanPlaceholders(statement string, fn func(start int, name string)) {
+	runes := []rune(statement)
+	var quote rune
+
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"' || r == '`':
+			quote = r
+		case r == '-' && i+1 < len(runes) && runes[i+1] == '-':
+			for i < len(runes) && runes[i] != '\n' {
+				i++
+			}
+		case r == '/' && i+1 < len(runes) && runes[i+1] == '*':
+			i += 2
+			for i < len(runes) && !(runes[i] == '*' && i+1 < len(runes) && runes[i+1] == '/') {
+				i++
+			}
+			i++
+		case r == ':' && i+1 < len(runes) && runes[i+1] == ':':
+			i++
+		case r == ':':
+			end := i + 1
+			for end < len(runes) && isPlaceholderRune(runes[end], end == i+1) {
+				end++
+			}
+
+			if end > i+1 {
+				fn(i, string(runes[i+1:end]))
+			}
+			i = end - 1
+		}
+	}
+}
+
+// isPlaceholderRune check if rune is valid in placeholder names, which do not start with digits
+func isPlaceholderRune(r rune, first bool) bool {
+	return r == '_' || unicode.IsLetter(r) || (!first && unicode.IsDigit(r))
+}
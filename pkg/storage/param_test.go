@@ -0,0 +1,63 @@
+package storage_test
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/storage"
+	"database/sql"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestShouldFindPlaceholdersWithSuccess(t *testing.T) {
+	tests := []struct {
+		statement string
+		expects   []string
+	}{
+		{statement: "select * from rows where date >= :start and date < :end and id = :id", expects: []string{"start", "end", "id"}},
+		{statement: "select @amount, $1, $name, [1, 2][:id] from rows where a = :value", expects: []string{"id", "value"}},
+		{statement: "select * from rows where a = :value or b = :value", expects: []string{"value"}},
+		{statement: "select ':quoted', json_extract(data, '$.a') -- :comment\nfrom rows /* :block */", expects: []string{}},
+		{statement: "select time(':1'), :1, :_name2 from rows", expects: []string{"_name2"}},
+		{statement: "select amount::INTEGER, id::text from rows where id = :id", expects: []string{"id"}},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.expects, storage.Placeholders(test.statement), test.statement)
+	}
+}
+
+func TestShouldBindParamsWithSuccess(t *testing.T) {
+	args := []any{storage.Param{Name: "start", Value: "2023-01-01"}, storage.Param{Name: "id", Value: "7"}}
+	assert.Equal(t, []any{sql.Named("start", "2023-01-01"), sql.Named("id", "7")}, storage.NamedArgs(args))
+
+	tests := []struct {
+		statement string
+		args      []any
+		expects   string
+		values    []any
+	}{
+		{
+			statement: "select amount::INTEGER, @amount from rows where id = :id and day >= :start and id = :id",
+			args:      args,
+			expects:   "select amount::INTEGER, @amount from rows where id = $id and day >= $start and id = $id",
+			values:    []any{"7", "2023-01-01"},
+		},
+		{
+			statement: "select ':start', 'ação' -- :comment\nfrom rows where a = :ação",
+			args:      []any{storage.Param{Name: "ação", Value: "1"}},
+			expects:   "select ':start', 'ação' -- :comment\nfrom rows where a = $ação",
+			values:    []any{"1"},
+		},
+		{
+			statement: "select * from rows where id = ?",
+			args:      []any{"1"},
+			expects:   "select * from rows where id = ?",
+			values:    []any{"1"},
+		},
+	}
+
+	for _, test := range tests {
+		statement, values := storage.PositionalArgs(test.statement, test.args)
+		assert.Equal(t, test.expects, statement)
+		assert.Equal(t, test.values, values, test.statement)
+	}
+}
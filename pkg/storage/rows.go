@@ -0,0 +1,215 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+)
+
+const (
+	dateType       = "DATE"
+	timeType       = "TIME"
+	dateLayout     = "2006-01-02"
+	timeLayout     = "15:04:05.999999"
+	dateTimeLayout = "2006-01-02 15:04:05.999999999"
+	zoneLayout     = "Z07:00"
+)
+
+// Column name and database type of a result column, the type is empty when the engine does not report it
+type Column struct {
+	Name string
+	Type string
+}
+
+// Rows result of a query read row by row, values are nil, bool, int64, float64, string or []byte,
+// dates and times are formatted as text
+type Rows interface {
+	Columns() []Column
+	Next() bool
+	Values() ([]any, error)
+	Err() error
+	Close() error
+}
+
+// ValueConverter convert values of engine specific types before they are normalized
+type ValueConverter func(column Column, value any) any
+
+// ColumnNames names of the columns
+func ColumnNames(columns []Column) []string {
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.Name
+	}
+
+	return names
+}
+
+type sqlRows struct {
+	rows    *sql.Rows
+	columns []Column
+	convert ValueConverter
+}
+
+// NewSqlRows read result of a database/sql query, convert may be nil, rows are closed when it fails
+func NewSqlRows(rows *sql.Rows, convert ValueConverter) (Rows, error) {
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		_ = rows.Close()
+		return nil, fmt.Errorf("failed to load columns: %w", err)
+	}
+
+	columns := make([]Column, len(types))
+	for i, t := range types {
+		columns[i] = Column{Name: t.Name(), Type: t.DatabaseTypeName()}
+	}
+
+	return &sqlRows{rows: rows, columns: columns, convert: convert}, nil
+}
+
+// Columns columns of the result
+func (r *sqlRows) Columns() []Column {
+	return r.columns
+}
+
+// Next move to the next row, false when there are no more rows or reading failed
+func (r *sqlRows) Next() bool {
+	return r.rows.Next()
+}
+
+// Values values of the current row
+func (r *sqlRows) Values() ([]any, error) {
+	values := make([]any, len(r.columns))
+	pointers := make([]any, len(r.columns))
+	for i := range values {
+		pointers[i] = &values[i]
+	}
+
+	if err := r.rows.Scan(pointers...); err != nil {
+		return nil, fmt.Errorf("failed to read row: %w", err)
+	}
+
+	for i, value := range values {
+		if r.convert != nil {
+			value = r.convert(r.columns[i], value)
+		}
+
+		values[i] = normalizeValue(r.columns[i], value)
+	}
+
+	return values, nil
+}
+
+// Err error that stopped reading rows
+func (r *sqlRows) Err() error {
+	return r.rows.Err()
+}
+
+// Close execute in defer
+func (r *sqlRows) Close() error {
+	return r.rows.Close()
+}
+
+type memoryRows struct {
+	columns []Column
+	records [][]any
+	current int
+}
+
+// NewRows result with the given rows, used to present catalog data and as a fake of query results
+func NewRows(columns []Column, records [][]any) Rows {
+	return &memoryRows{columns: columns, records: records, current: -1}
+}
+
+// Columns columns of the result
+func (r *memoryRows) Columns() []Column {
+	return r.columns
+}
+
+// Next move to the next row, false when there are no more rows
+func (r *memoryRows) Next() bool {
+	if r.current < len(r.records) {
+		r.current++
+	}
+
+	return r.current < len(r.records)
+}
+
+// Values values of the current row
+func (r *memoryRows) Values() ([]any, error) {
+	if r.current < 0 || r.current >= len(r.records) {
+		return nil, fmt.Errorf("failed to read row: no current row")
+	}
+
+	return r.records[r.current], nil
+}
+
+// Err always nil, rows in memory can not fail
+func (r *memoryRows) Err() error {
+	return nil
+}
+
+// Close execute in defer
+func (r *memoryRows) Close() error {
+	r.current = len(r.records)
+	return nil
+}
+
+// normalizeValue convert value returned by the driver to one of the types of Rows
+func normalizeValue(column Column, value any) any {
+	switch v := value.(type) {
+	case int:
+		return int64(v)
+	case int8:
+		return int64(v)
+	case int16:
+		return int64(v)
+	case int32:
+		return int64(v)
+	case uint:
+		return normalizeUnsigned(uint64(v))
+	case uint8:
+		return int64(v)
+	case uint16:
+		return int64(v)
+	case uint32:
+		return int64(v)
+	case uint64:
+		return normalizeUnsigned(v)
+	case float32:
+		parsed, _ := strconv.ParseFloat(strconv.FormatFloat(float64(v), 'g', -1, 32), 64)
+		return parsed
+	case time.Time:
+		return formatTime(column, v)
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return value
+	}
+}
+
+// normalizeUnsigned unsigned value as int64, as text when it does not fit
+func normalizeUnsigned(value uint64) any {
+	if value > math.MaxInt64 {
+		return strconv.FormatUint(value, 10)
+	}
+
+	return int64(value)
+}
+
+// formatTime format date and time values by the column type, the zone is kept only when it is not UTC
+func formatTime(column Column, value time.Time) string {
+	switch column.Type {
+	case dateType:
+		return value.Format(dateLayout)
+	case timeType:
+		return value.Format(timeLayout)
+	}
+
+	if value.Location() == time.UTC {
+		return value.Format(dateTimeLayout)
+	}
+
+	return value.Format(dateTimeLayout + zoneLayout)
+}
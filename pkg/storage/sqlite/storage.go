@@ -2,23 +2,46 @@ package sqlite
 
 import (
 	"adrianolaselva.github.io/csvql/pkg/storage"
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	_ "github.com/mattn/go-sqlite3"
+	"os"
 	"strings"
 )
 
 const (
 	sqlCreateTableTemplate        = "CREATE TABLE IF NOT EXISTS %s (%s\n);"
 	sqlInsertTemplate             = "INSERT INTO %s (%s) VALUES (%s);"
-	sqlInsertDefaultTableTemplate = "INSERT INTO `schemas` (`id`, `name`, `columns`, `total_columns`) VALUES ((select count(1)+1 FROM `schemas`),?,?,?);"
-	sqlShowTablesTemplate         = "select * from `schemas`;"
+	sqlInsertIgnoreTemplate       = "INSERT INTO %s (%s) VALUES (%s) ON CONFLICT DO NOTHING;"
+	sqlUpsertTemplate             = "INSERT INTO %s (%s) VALUES (%s) ON CONFLICT (%s) DO UPDATE SET %s WHERE %s;"
+	sqlCreateIndexTemplate        = "CREATE %sINDEX IF NOT EXISTS `%s` ON %s (%s);"
+	sqlInsertDefaultTableTemplate = "INSERT INTO `schemas` (`id`, `name`, `columns`, `total_columns`) SELECT (select count(1)+1 FROM `schemas`),?,?,? WHERE NOT EXISTS (select 1 FROM `schemas` WHERE `name` = ?);"
+	sqlListTablesTemplate         = "select `id`, `name`, `columns`, `total_columns` from `schemas` order by `id`;"
+	sqlShowSchemaTemplate         = "select `sql` from sqlite_master where tbl_name = ? and `sql` is not null order by type desc;"
+	sqlDescribeTableTemplate      = "select cid, name, type, `notnull`, dflt_value, pk from pragma_table_info(?);"
 	sqlDefaultTableTemplate       = "CREATE TABLE IF NOT EXISTS `schemas` (`id` INTEGER, `name` text, `columns` text, `total_columns` INTEGER);"
+	sqlImportsTableTemplate       = "CREATE TABLE IF NOT EXISTS `imports` (`name` text, `path` text, `size` INTEGER, `mod_time` INTEGER, `hash` text, `options` text, primary key (`name`, `path`));"
+	sqlSelectImportTemplate       = "select `path`, `size`, `mod_time`, `hash`, `options` from `imports` where `name` = ? and `path` = ?;"
+	sqlReplaceImportTemplate      = "INSERT OR REPLACE INTO `imports` (`name`, `path`, `size`, `mod_time`, `hash`, `options`) VALUES (?,?,?,?,?,?);"
+	sqlListIndexesTemplate        = "select `name`, `tbl_name` `table`, `sql` from sqlite_master where type = 'index' and `sql` is not null order by `tbl_name`, `name`;"
+	sqlHasTableTemplate           = "select count(1) from sqlite_master where type in ('table', 'view') and name = ?;"
+	sqlDropTableTemplate          = "DROP TABLE IF EXISTS `%s`;"
+	sqlDeleteSchemaTemplate       = "DELETE FROM `schemas` WHERE `name` = ?;"
+	sqlDeleteImportTemplate       = "DELETE FROM `imports` WHERE `name` = ?;"
+	sqlMatchRowsTemplate          = "with `csvql_rows` (`csvql_row`, %s) as (values %s) select `csvql_row` from `csvql_rows` where %s;"
 	dataSourceNameDefault         = ":memory:"
+	tempDataSourceTemplate        = "%s?_cache_size=-%d&_journal_mode=OFF&_sync=OFF"
+	tempFilePattern               = "csvql-*.db"
 )
 
+// driverName sqlite driver, replaced by the driver registering the csv module when built with virtual tables
+var driverName = "sqlite3"
+
 type sqLiteStorage struct {
-	db *sql.DB
+	db       *sql.DB
+	tempFile string
 }
 
 func NewSqLiteStorage(datasource string) (storage.Storage, error) {
@@ -26,7 +49,7 @@ func NewSqLiteStorage(datasource string) (storage.Storage, error) {
 		datasource = dataSourceNameDefault
 	}
 
-	db, err := sql.Open("sqlite3", datasource)
+	db, err := sql.Open(driverName, datasource)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open connection with sqlite3: %w", err)
 	}
@@ -34,6 +57,27 @@ func NewSqLiteStorage(datasource string) (storage.Storage, error) {
 	return &sqLiteStorage{db: db}, nil
 }
 
+// NewTempSqLiteStorage create storage in a temporary file removed on Close, for data larger than the memory,
+// the page cache uses up to cacheSize bytes and journaling is disabled since the file is discarded
+func NewTempSqLiteStorage(cacheSize int64) (storage.Storage, error) {
+	file, err := os.CreateTemp("", tempFilePattern)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create temporary sqlite3 file: %w", err)
+	}
+
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("failed to create temporary sqlite3 file: %w", err)
+	}
+
+	db, err := sql.Open(driverName, fmt.Sprintf(tempDataSourceTemplate, file.Name(), cacheSize/1024))
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return nil, fmt.Errorf("failed to open connection with sqlite3: %w", err)
+	}
+
+	return &sqLiteStorage{db: db, tempFile: file.Name()}, nil
+}
+
 // BuildStructure build table creation statement
 func (s *sqLiteStorage) BuildStructure(tableName string, columns []string) error {
 	var tableAttrsRaw strings.Builder
@@ -59,7 +103,7 @@ func (s *sqLiteStorage) BuildStructure(tableName string, columns []string) error
 	}
 
 	columnsRaw := fmt.Sprintf("[%v]", strings.Join(columns, ","))
-	if _, err := s.db.Exec(sqlInsertDefaultTableTemplate, []any{tableName, columnsRaw, len(columns)}...); err != nil {
+	if _, err := s.db.Exec(sqlInsertDefaultTableTemplate, []any{tableName, columnsRaw, len(columns), tableName}...); err != nil {
 		return fmt.Errorf("failed to execute insert: %w", err)
 	}
 
@@ -79,23 +123,346 @@ func (s *sqLiteStorage) InsertRow(tableName string, columns []string, values []a
 	return nil
 }
 
-// Query execute statements
-func (s *sqLiteStorage) Query(cmd string) (*sql.Rows, error) {
-	rows, err := s.db.Query(cmd)
+// MatchRows check which rows match the where expression with a single query, values are compared as text like
+// imported columns
+func (s *sqLiteStorage) MatchRows(columns []string, rows [][]any, where string) ([]bool, error) {
+	matches := make([]bool, len(rows))
+	if len(rows) == 0 {
+		return matches, nil
+	}
+
+	quoted := make([]string, len(columns))
+	fields := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdentifier(c)
+		fields[i] = "CAST(? AS TEXT)"
+	}
+
+	records := make([]string, len(rows))
+	args := make([]any, 0, len(rows)*len(columns))
+	for i, values := range rows {
+		records[i] = fmt.Sprintf("(%d, %s)", i, strings.Join(fields, ", "))
+		args = append(args, values...)
+	}
+
+	query := fmt.Sprintf(sqlMatchRowsTemplate, strings.Join(quoted, ", "), strings.Join(records, ", "), where)
+	result, err := s.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute query: %w", err)
+		return nil, fmt.Errorf("failed to evaluate where %s: %w", where, err)
 	}
+	defer func(result *sql.Rows) {
+		_ = result.Close()
+	}(result)
+
+	for result.Next() {
+		var i int
+		if err := result.Scan(&i); err != nil {
+			return nil, fmt.Errorf("failed to evaluate where %s: %w", where, err)
+		}
 
-	return rows, nil
+		matches[i] = true
+	}
+
+	if err := result.Err(); err != nil {
+		return nil, fmt.Errorf("failed to evaluate where %s: %w", where, err)
+	}
+
+	return matches, nil
 }
 
-func (s *sqLiteStorage) ShowTables() (*sql.Rows, error) {
-	rows, err := s.db.Query(sqlShowTablesTemplate)
+// ListIndexes list indexes created on tables
+func (s *sqLiteStorage) ListIndexes() ([]storage.Index, error) {
+	rows, err := s.db.Query(sqlListIndexesTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	indexes := make([]storage.Index, 0)
+	for rows.Next() {
+		var index storage.Index
+		if err := rows.Scan(&index.Name, &index.Table, &index.SQL); err != nil {
+			return nil, fmt.Errorf("failed to list indexes: %w", err)
+		}
+
+		indexes = append(indexes, index)
+	}
+
+	return indexes, rows.Err()
+}
+
+// HasTable check if table exists
+func (s *sqLiteStorage) HasTable(tableName string) (bool, error) {
+	var total int
+	if err := s.db.QueryRow(sqlHasTableTemplate, tableName).Scan(&total); err != nil {
+		return false, fmt.Errorf("failed to check table %s: %w", tableName, err)
+	}
+
+	return total > 0, nil
+}
+
+// DropTable drop table and remove it from the catalog
+func (s *sqLiteStorage) DropTable(tableName string) error {
+	if err := s.buildCatalog(); err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(fmt.Sprintf(sqlDropTableTemplate, tableName)); err != nil {
+		return fmt.Errorf("failed to drop table %s: %w", tableName, err)
+	}
+
+	if _, err := s.db.Exec(sqlDeleteSchemaTemplate, tableName); err != nil {
+		return fmt.Errorf("failed to remove table %s from schemas: %w", tableName, err)
+	}
+
+	if _, err := s.db.Exec(sqlDeleteImportTemplate, tableName); err != nil {
+		return fmt.Errorf("failed to remove table %s from imports: %w", tableName, err)
+	}
+
+	return nil
+}
+
+// LoadFingerprint load fingerprint of the file imported into the table, ok is false when there is none
+func (s *sqLiteStorage) LoadFingerprint(tableName string, path string) (storage.Fingerprint, bool, error) {
+	if err := s.buildCatalog(); err != nil {
+		return storage.Fingerprint{}, false, err
+	}
+
+	var fp storage.Fingerprint
+	err := s.db.QueryRow(sqlSelectImportTemplate, tableName, path).Scan(&fp.Path, &fp.Size, &fp.ModTime, &fp.Hash, &fp.Options)
+	if errors.Is(err, sql.ErrNoRows) {
+		return storage.Fingerprint{}, false, nil
+	}
+
+	if err != nil {
+		return storage.Fingerprint{}, false, fmt.Errorf("failed to load fingerprint of table %s: %w", tableName, err)
+	}
+
+	return fp, true, nil
+}
+
+// SaveFingerprint record fingerprint of the file imported into the table
+func (s *sqLiteStorage) SaveFingerprint(tableName string, fp storage.Fingerprint) error {
+	if err := s.buildCatalog(); err != nil {
+		return err
+	}
+
+	if _, err := s.db.Exec(sqlReplaceImportTemplate, tableName, fp.Path, fp.Size, fp.ModTime, fp.Hash, fp.Options); err != nil {
+		return fmt.Errorf("failed to save fingerprint of table %s: %w", tableName, err)
+	}
+
+	return nil
+}
+
+// buildCatalog create catalog tables when missing
+func (s *sqLiteStorage) buildCatalog() error {
+	if _, err := s.db.Exec(sqlDefaultTableTemplate); err != nil {
+		return fmt.Errorf("failed to create tables schemas structure: %w", err)
+	}
+
+	if _, err := s.db.Exec(sqlImportsTableTemplate); err != nil {
+		return fmt.Errorf("failed to create imports structure: %w", err)
+	}
+
+	return nil
+}
+
+// UpsertRow insert row or update the row with the same keys, changed is false when the existing row is equal
+func (s *sqLiteStorage) UpsertRow(tableName string, columns []string, keys []string, values []any) (bool, error) {
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdentifier(c)
+	}
+
+	isKey := make(map[string]bool)
+	quotedKeys := make([]string, len(keys))
+	for i, k := range keys {
+		quotedKeys[i] = quoteIdentifier(k)
+		isKey[quotedKeys[i]] = true
+	}
+
+	sets := make([]string, 0, len(columns))
+	changes := make([]string, 0, len(columns))
+	for _, c := range quoted {
+		if isKey[c] {
+			continue
+		}
+
+		sets = append(sets, fmt.Sprintf("%s = excluded.%s", c, c))
+		changes = append(changes, fmt.Sprintf("%s.%s IS NOT excluded.%s", tableName, c, c))
+	}
+
+	paramsRaw := strings.Repeat("?, ", len(columns))
+	query := fmt.Sprintf(sqlInsertIgnoreTemplate, tableName, strings.Join(quoted, ", "), paramsRaw[:len(paramsRaw)-2])
+	if len(sets) > 0 {
+		query = fmt.Sprintf(sqlUpsertTemplate, tableName, strings.Join(quoted, ", "), paramsRaw[:len(paramsRaw)-2],
+			strings.Join(quotedKeys, ", "), strings.Join(sets, ", "), strings.Join(changes, " OR "))
+	}
+
+	result, err := s.db.Exec(query, values...)
+	if err != nil {
+		return false, fmt.Errorf("failed to execute upsert: %w (sql: %s)", err, query)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to load affected rows: %w", err)
+	}
+
+	return affected > 0, nil
+}
+
+// CreateIndex create index on the columns of the table when missing
+func (s *sqLiteStorage) CreateIndex(tableName string, columns []string, unique bool) error {
+	quoted := make([]string, len(columns))
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdentifier(c)
+		names[i] = strings.Trim(c, "`")
+	}
+
+	kind, suffix := "", "idx"
+	if unique {
+		kind, suffix = "UNIQUE ", "key"
+	}
+
+	name := fmt.Sprintf("%s_%s_%s", strings.Trim(tableName, "`"), strings.Join(names, "_"), suffix)
+	query := fmt.Sprintf(sqlCreateIndexTemplate, kind, name, tableName, strings.Join(quoted, ", "))
+	if _, err := s.db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create index: %w (sql: %s)", err, query)
+	}
+
+	return nil
+}
+
+// Query execute statements, binding args and params to its placeholders
+func (s *sqLiteStorage) Query(cmd string, args ...any) (storage.Rows, error) {
+	return s.QueryContext(context.Background(), cmd, args...)
+}
+
+// ExecContext execute statements that do not return rows, returning the number of affected rows
+func (s *sqLiteStorage) ExecContext(ctx context.Context, cmd string, args ...any) (int64, error) {
+	defer collationGuard(cmd)()
+
+	result, err := s.db.ExecContext(ctx, cmd, storage.NamedArgs(args)...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to execute statement: %w", err)
+	}
+
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load affected rows: %w", err)
+	}
+
+	return affected, nil
+}
+
+// QueryContext execute statements, interrupting them when the context is done
+func (s *sqLiteStorage) QueryContext(ctx context.Context, cmd string, args ...any) (storage.Rows, error) {
+	defer collationGuard(cmd)()
+
+	rows, err := s.db.QueryContext(ctx, cmd, storage.NamedArgs(args)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
 
-	return rows, nil
+	return storage.NewSqlRows(rows, nil)
+}
+
+// ListTables list tables of the schemas catalog in the order they were created
+func (s *sqLiteStorage) ListTables() ([]storage.Table, error) {
+	if err := s.buildCatalog(); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query(sqlListTablesTemplate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	tables := make([]storage.Table, 0)
+	for rows.Next() {
+		var table storage.Table
+		if err := rows.Scan(&table.ID, &table.Name, &table.Columns, &table.TotalColumns); err != nil {
+			return nil, fmt.Errorf("failed to list tables: %w", err)
+		}
+
+		tables = append(tables, table)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	return tables, nil
+}
+
+// ShowSchema list statements used to create table and its indexes
+func (s *sqLiteStorage) ShowSchema(tableName string) ([]string, error) {
+	statements, err := s.queryStrings(sqlShowSchemaTemplate, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema of table %s: %w", tableName, err)
+	}
+
+	return statements, nil
+}
+
+// DescribeTable list columns of table
+func (s *sqLiteStorage) DescribeTable(tableName string) ([]storage.TableColumn, error) {
+	rows, err := s.db.Query(sqlDescribeTableTemplate, tableName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe table %s: %w", tableName, err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	columns := make([]storage.TableColumn, 0)
+	for rows.Next() {
+		var column storage.TableColumn
+		var cid, pk int64
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &column.Name, &column.Type, &column.NotNull, &dflt, &pk); err != nil {
+			return nil, fmt.Errorf("failed to describe table %s: %w", tableName, err)
+		}
+
+		if dflt.Valid {
+			column.Default = &dflt.String
+		}
+
+		column.PrimaryKey = pk > 0
+		columns = append(columns, column)
+	}
+
+	return columns, rows.Err()
+}
+
+// queryStrings values of the single column returned by the query
+func (s *sqLiteStorage) queryStrings(query string, args ...any) ([]string, error) {
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	values := make([]string, 0)
+	for rows.Next() {
+		var value string
+		if err := rows.Scan(&value); err != nil {
+			return nil, err
+		}
+
+		values = append(values, value)
+	}
+
+	return values, rows.Err()
 }
 
 // Close execute in defer
@@ -105,5 +472,22 @@ func (s *sqLiteStorage) Close() error {
 		return fmt.Errorf("failed to close sqlite3 connection: %w", err)
 	}
 
+	if s.tempFile == "" {
+		return nil
+	}
+
+	if err := os.Remove(s.tempFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove temporary sqlite3 file: %w", err)
+	}
+
 	return nil
 }
+
+// quoteIdentifier quote column name with backticks when not quoted yet
+func quoteIdentifier(name string) string {
+	if strings.HasPrefix(name, "`") && strings.HasSuffix(name, "`") {
+		return name
+	}
+
+	return fmt.Sprintf("`%s`", name)
+}
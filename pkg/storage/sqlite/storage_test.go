@@ -1,9 +1,12 @@
 package sqlite_test
 
 import (
+	"adrianolaselva.github.io/csvql/pkg/storage"
 	"adrianolaselva.github.io/csvql/pkg/storage/sqlite"
+	"context"
 	"github.com/stretchr/testify/assert"
 	"testing"
+	"time"
 )
 
 func TestShouldBuildStructureWithSuccess(t *testing.T) {
@@ -112,21 +115,17 @@ func TestShouldBuildStructureWithSuccess(t *testing.T) {
 		rows, err := storage.Query(test.query)
 		assert.NoError(t, err)
 
-		cols, err := rows.Columns()
-		assert.NoError(t, err)
+		cols := make([]string, 0)
+		for _, c := range rows.Columns() {
+			cols = append(cols, c.Name)
+		}
 		assert.Equal(t, test.columnExpects, cols)
 
 		for _, expected := range test.rowsExpects {
 			rs := rows.Next()
 			assert.True(t, rs)
 
-			values := make([]interface{}, len(test.columnExpects))
-			pointers := make([]interface{}, len(test.columnExpects))
-			for i := range values {
-				pointers[i] = &values[i]
-			}
-
-			err = rows.Scan(pointers...)
+			values, err := rows.Values()
 			assert.NoError(t, err)
 
 			assert.Equal(t, expected, values)
@@ -139,3 +138,109 @@ func TestShouldBuildStructureWithSuccess(t *testing.T) {
 		assert.NoError(t, err)
 	}
 }
+
+func TestShouldInterruptQueryWhenContextIsDone(t *testing.T) {
+	storage, err := sqlite.NewSqLiteStorage(":memory:")
+	assert.NoError(t, err)
+	defer func() {
+		_ = storage.Close()
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	rows, err := storage.QueryContext(ctx, "with recursive r(n) as (select 1 union all select n + 1 from r) select count(*) from r;")
+	if err == nil {
+		for rows.Next() {
+		}
+		err = rows.Err()
+		_ = rows.Close()
+	}
+
+	assert.Error(t, err)
+	assert.ErrorIs(t, ctx.Err(), context.DeadlineExceeded)
+}
+
+func TestShouldExecuteStatementsWithSuccess(t *testing.T) {
+	storage, err := sqlite.NewSqLiteStorage(":memory:")
+	assert.NoError(t, err)
+	defer func() {
+		_ = storage.Close()
+	}()
+
+	assert.NoError(t, storage.BuildStructure("rows", []string{"column_1"}))
+
+	affected, err := storage.ExecContext(context.Background(), "insert into rows values ('value_1'), ('value_2');")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), affected)
+
+	affected, err = storage.ExecContext(context.Background(), "update rows set column_1 = 'value' where column_1 = 'value_1';")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), affected)
+}
+
+func TestShouldListTablesAndBindParamsWithSuccess(t *testing.T) {
+	s, err := sqlite.NewSqLiteStorage(":memory:")
+	assert.NoError(t, err)
+	defer func(s storage.Storage) {
+		_ = s.Close()
+	}(s)
+
+	assert.NoError(t, s.BuildStructure("rows", []string{"id", "name"}))
+	assert.NoError(t, s.InsertRow("rows", []string{"`id`", "`name`"}, []any{"1", "value_1"}))
+	assert.NoError(t, s.InsertRow("rows", []string{"`id`", "`name`"}, []any{"2", "value_2"}))
+
+	tables, err := s.ListTables()
+	assert.NoError(t, err)
+	assert.Equal(t, []storage.Table{{ID: 1, Name: "rows", Columns: "[`id`,`name`]", TotalColumns: 2}}, tables)
+
+	rows, err := s.Query("select name from rows where id = :id and name = :name;",
+		storage.Param{Name: "name", Value: "value_2"}, storage.Param{Name: "id", Value: "2"})
+	assert.NoError(t, err)
+	defer func(rows storage.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	assert.True(t, rows.Next())
+	values, err := rows.Values()
+	assert.NoError(t, err)
+	assert.Equal(t, []any{"value_2"}, values)
+	assert.False(t, rows.Next())
+}
+
+func TestShouldRemoveTemporaryStorageOnCloseWithSuccess(t *testing.T) {
+	storage, err := sqlite.NewTempSqLiteStorage(1024 * 1024)
+	assert.NoError(t, err)
+	assert.NoError(t, storage.BuildStructure("rows", []string{"id"}))
+	assert.NoError(t, storage.InsertRow("rows", []string{"`id`"}, []any{"1"}))
+
+	rows, err := storage.Query("select file from pragma_database_list where name = 'main';")
+	assert.NoError(t, err)
+
+	assert.True(t, rows.Next())
+	values, err := rows.Values()
+	assert.NoError(t, err)
+	assert.NoError(t, rows.Close())
+
+	file := values[0].(string)
+	assert.FileExists(t, file)
+
+	assert.NoError(t, storage.Close())
+	assert.NoFileExists(t, file)
+}
+
+func TestShouldMatchRowsWithSuccess(t *testing.T) {
+	s, err := sqlite.NewSqLiteStorage(":memory:")
+	assert.NoError(t, err)
+	defer func(s storage.Storage) {
+		_ = s.Close()
+	}(s)
+
+	rows := [][]any{{"1", "north"}, {"2", "south"}, {"3", "north"}}
+	matches, err := s.MatchRows([]string{"id", "region"}, rows, "region = 'north' and id > '1'")
+	assert.NoError(t, err)
+	assert.Equal(t, []bool{false, false, true}, matches)
+
+	_, err = s.MatchRows([]string{"id", "region"}, rows, "missing = 1")
+	assert.Error(t, err)
+}
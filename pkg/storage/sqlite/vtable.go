@@ -0,0 +1,488 @@
+//go:build sqlite_vtable
+
+package sqlite
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+const (
+	vtableDriverName               = "sqlite3_csvql"
+	vtableModuleName               = "csvql"
+	sqlCreateVirtualTableTemplate  = "CREATE VIRTUAL TABLE `%s` USING %s(filename=%s, delimiter=%s, stats=%d);"
+	sqlDeclareVirtualTableTemplate = "CREATE TABLE x (%s);"
+	sqlVirtualTableColumnsTemplate = "select name from pragma_table_info(?) order by cid;"
+	virtualTableScanCost           = 1e9
+	virtualTableFilterCost         = 1e6
+)
+
+var (
+	collateRegex = regexp.MustCompile(`(?i)\bcollate\b`)
+	// collatedStatements statements with an explicit collation being prepared
+	collatedStatements int32
+)
+
+func init() {
+	sql.Register(vtableDriverName, &sqlite3.SQLiteDriver{
+		ConnectHook: func(conn *sqlite3.SQLiteConn) error {
+			return conn.CreateModule(vtableModuleName, &csvModule{})
+		},
+	})
+
+	driverName = vtableDriverName
+}
+
+// CreateVirtualTable create table reading the csv file on each query instead of importing it,
+// stats keeps per block min/max values in a sidecar file used to skip blocks that can not match filters
+func (s *sqLiteStorage) CreateVirtualTable(tableName string, path string, delimiter rune, stats bool) error {
+	if err := s.buildCatalog(); err != nil {
+		return err
+	}
+
+	withStats := 0
+	if stats {
+		withStats = 1
+	}
+
+	query := fmt.Sprintf(sqlCreateVirtualTableTemplate, tableName, vtableModuleName,
+		quoteLiteral(path), quoteLiteral(string(delimiter)), withStats)
+	if _, err := s.db.Exec(query); err != nil {
+		return fmt.Errorf("failed to create virtual table %s: %w", tableName, err)
+	}
+
+	rows, err := s.db.Query(sqlVirtualTableColumnsTemplate, tableName)
+	if err != nil {
+		return fmt.Errorf("failed to load columns of virtual table %s: %w", tableName, err)
+	}
+	defer func(rows *sql.Rows) {
+		_ = rows.Close()
+	}(rows)
+
+	columns := make([]string, 0)
+	for rows.Next() {
+		var column string
+		if err := rows.Scan(&column); err != nil {
+			return fmt.Errorf("failed to load columns of virtual table %s: %w", tableName, err)
+		}
+		columns = append(columns, quoteIdentifier(column))
+	}
+
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to load columns of virtual table %s: %w", tableName, err)
+	}
+
+	columnsRaw := fmt.Sprintf("[%v]", strings.Join(columns, ","))
+	if _, err := s.db.Exec(sqlInsertDefaultTableTemplate, tableName, columnsRaw, len(columns), tableName); err != nil {
+		return fmt.Errorf("failed to execute insert: %w", err)
+	}
+
+	return nil
+}
+
+// quoteLiteral quote value as sql string literal
+func quoteLiteral(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// csvModule virtual table module streaming csv files
+type csvModule struct{}
+
+// Create create virtual table from the module arguments
+func (m *csvModule) Create(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	return m.Connect(c, args)
+}
+
+// Connect connect to virtual table, reading the header of the csv file to declare its columns
+func (m *csvModule) Connect(c *sqlite3.SQLiteConn, args []string) (sqlite3.VTab, error) {
+	table, err := newCsvTable(args)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, len(table.columns))
+	for i, column := range table.columns {
+		columns[i] = fmt.Sprintf("%s text", quoteIdentifier(strings.ReplaceAll(column, "`", "``")))
+	}
+
+	if err := c.DeclareVTab(fmt.Sprintf(sqlDeclareVirtualTableTemplate, strings.Join(columns, ", "))); err != nil {
+		return nil, fmt.Errorf("failed to declare virtual table: %w", err)
+	}
+
+	return table, nil
+}
+
+// DestroyModule nothing to release
+func (m *csvModule) DestroyModule() {}
+
+// csvTable virtual table over a csv file
+type csvTable struct {
+	path      string
+	delimiter rune
+	columns   []string
+	stats     *csvStats
+}
+
+// newCsvTable parse module arguments `filename`, `delimiter` and `stats`
+func newCsvTable(args []string) (*csvTable, error) {
+	table := &csvTable{delimiter: ','}
+	withStats := false
+
+	for _, arg := range args[3:] {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid virtual table argument %s", arg)
+		}
+
+		value = strings.TrimSpace(value)
+		if unquoted, err := unquoteLiteral(value); err == nil {
+			value = unquoted
+		}
+
+		switch strings.TrimSpace(key) {
+		case "filename":
+			table.path = value
+		case "delimiter":
+			runes := []rune(value)
+			if len(runes) != 1 {
+				return nil, fmt.Errorf("invalid virtual table delimiter %s", value)
+			}
+			table.delimiter = runes[0]
+		case "stats":
+			withStats = value == "1"
+		default:
+			return nil, fmt.Errorf("invalid virtual table argument %s", arg)
+		}
+	}
+
+	if table.path == "" {
+		return nil, errors.New("virtual table requires the filename argument")
+	}
+
+	file, err := os.Open(table.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	r := csv.NewReader(file)
+	r.Comma = table.delimiter
+	if table.columns, err = r.Read(); err != nil {
+		return nil, fmt.Errorf("failed to load headers: %w", err)
+	}
+
+	if withStats {
+		if table.stats, err = loadStats(table.path, table.delimiter); err != nil {
+			return nil, err
+		}
+	}
+
+	return table, nil
+}
+
+// unquoteLiteral remove quotes of sql string literal
+func unquoteLiteral(value string) (string, error) {
+	if len(value) < 2 || value[0] != '\'' || value[len(value)-1] != '\'' {
+		return "", fmt.Errorf("invalid literal %s", value)
+	}
+
+	return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+}
+
+// collationGuard disable filters of virtual tables while preparing a statement with an explicit collation, the
+// driver neither reports the collation of constraints nor lets sqlite check them again and the cursor compares
+// as binary, release in defer
+func collationGuard(cmd string) func() {
+	if !collateRegex.MatchString(cmd) {
+		return func() {}
+	}
+
+	atomic.AddInt32(&collatedStatements, 1)
+	return func() {
+		atomic.AddInt32(&collatedStatements, -1)
+	}
+}
+
+// BestIndex use comparison filters to skip blocks when the table has stats, the cursor then checks them on each row
+func (t *csvTable) BestIndex(constraints []sqlite3.InfoConstraint, _ []sqlite3.InfoOrderBy) (*sqlite3.IndexResult, error) {
+	used := make([]bool, len(constraints))
+	if t.stats == nil || atomic.LoadInt32(&collatedStatements) > 0 {
+		return &sqlite3.IndexResult{Used: used, EstimatedCost: virtualTableScanCost}, nil
+	}
+
+	filters := make([]string, 0, len(constraints))
+	for i, constraint := range constraints {
+		if !constraint.Usable || constraint.Column < 0 || constraint.Column >= len(t.columns) {
+			continue
+		}
+
+		switch constraint.Op {
+		case sqlite3.OpEQ, sqlite3.OpGT, sqlite3.OpGE, sqlite3.OpLT, sqlite3.OpLE:
+			used[i] = true
+			filters = append(filters, fmt.Sprintf("%d:%d", constraint.Column, constraint.Op))
+		}
+	}
+
+	cost := float64(virtualTableScanCost)
+	if len(filters) > 0 {
+		cost = virtualTableFilterCost
+	}
+
+	return &sqlite3.IndexResult{Used: used, IdxStr: strings.Join(filters, ","), EstimatedCost: cost}, nil
+}
+
+// Disconnect nothing to release
+func (t *csvTable) Disconnect() error {
+	return nil
+}
+
+// Destroy nothing to release, the csv file is kept
+func (t *csvTable) Destroy() error {
+	return nil
+}
+
+// Open open cursor over the csv file
+func (t *csvTable) Open() (sqlite3.VTabCursor, error) {
+	file, err := os.Open(t.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	return &csvCursor{table: t, file: file}, nil
+}
+
+// csvFilter comparison of a column against a value
+type csvFilter struct {
+	column int
+	op     sqlite3.Op
+	value  any
+}
+
+// csvCursor cursor reading the csv file, only the blocks that may match the filters when the table has stats
+type csvCursor struct {
+	table     *csvTable
+	file      *os.File
+	reader    *csv.Reader
+	filters   []csvFilter
+	blocks    []csvBlock
+	remaining int64
+	record    []string
+	rowid     int64
+	eof       bool
+}
+
+// Filter start a scan, vals are the values compared by the filters chosen by BestIndex
+func (c *csvCursor) Filter(_ int, idxStr string, vals []any) error {
+	c.filters = c.filters[:0]
+	if idxStr != "" {
+		for i, raw := range strings.Split(idxStr, ",") {
+			column, op, _ := strings.Cut(raw, ":")
+			col, err := strconv.Atoi(column)
+			if err != nil {
+				return fmt.Errorf("invalid virtual table filter %s: %w", raw, err)
+			}
+
+			o, err := strconv.Atoi(op)
+			if err != nil {
+				return fmt.Errorf("invalid virtual table filter %s: %w", raw, err)
+			}
+
+			c.filters = append(c.filters, csvFilter{column: col, op: sqlite3.Op(o), value: vals[i]})
+		}
+	}
+
+	c.eof = false
+	c.blocks = nil
+	if c.table.stats != nil {
+		c.blocks = c.table.stats.candidates(c.filters)
+		if len(c.blocks) == 0 {
+			c.eof = true
+			return nil
+		}
+
+		if err := c.nextBlock(); err != nil {
+			return err
+		}
+
+		return c.Next()
+	}
+
+	if err := c.seek(0); err != nil {
+		return err
+	}
+
+	if _, err := c.reader.Read(); err != nil {
+		if errors.Is(err, io.EOF) {
+			c.eof = true
+			return nil
+		}
+
+		return fmt.Errorf("failed to load headers: %w", err)
+	}
+
+	c.rowid = 0
+	c.remaining = -1
+
+	return c.Next()
+}
+
+// Next move to the next row matching the filters
+func (c *csvCursor) Next() error {
+	for {
+		if c.remaining == 0 {
+			if len(c.blocks) == 0 {
+				c.eof = true
+				return nil
+			}
+
+			if err := c.nextBlock(); err != nil {
+				return err
+			}
+		}
+
+		record, err := c.reader.Read()
+		if errors.Is(err, io.EOF) {
+			c.eof = true
+			return nil
+		}
+
+		if err != nil {
+			return fmt.Errorf("failed to read line: %w", err)
+		}
+
+		c.record = record
+		c.rowid++
+		if c.remaining > 0 {
+			c.remaining--
+		}
+
+		if c.matches() {
+			return nil
+		}
+	}
+}
+
+// nextBlock move the reader to the start of the next candidate block
+func (c *csvCursor) nextBlock() error {
+	block := c.blocks[0]
+	c.blocks = c.blocks[1:]
+
+	if err := c.seek(block.Offset); err != nil {
+		return err
+	}
+
+	c.rowid = block.Row
+	c.remaining = block.Rows
+
+	return nil
+}
+
+// seek move the reader to the byte offset of the file
+func (c *csvCursor) seek(offset int64) error {
+	if _, err := c.file.Seek(offset, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to seek file: %w", err)
+	}
+
+	c.reader = csv.NewReader(c.file)
+	c.reader.Comma = c.table.delimiter
+	c.reader.FieldsPerRecord = -1
+
+	return nil
+}
+
+// matches check the current row against all filters
+func (c *csvCursor) matches() bool {
+	for _, f := range c.filters {
+		if f.column >= len(c.record) || !compareText(c.record[f.column], f.op, f.value) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// EOF check if the scan reached the end
+func (c *csvCursor) EOF() bool {
+	return c.eof
+}
+
+// Column result the value of the column in the current row, missing fields are null
+func (c *csvCursor) Column(ctx *sqlite3.SQLiteContext, col int) error {
+	if col < len(c.record) {
+		ctx.ResultText(c.record[col])
+		return nil
+	}
+
+	ctx.ResultNull()
+	return nil
+}
+
+// Rowid line number of the current row, starting at 1 after the header
+func (c *csvCursor) Rowid() (int64, error) {
+	return c.rowid, nil
+}
+
+// Close close the csv file
+func (c *csvCursor) Close() error {
+	return c.file.Close()
+}
+
+// compareText compare text column value against value as sqlite does for columns with text affinity
+func compareText(text string, op sqlite3.Op, value any) bool {
+	var cmp int
+	switch v := value.(type) {
+	case string:
+		cmp = strings.Compare(text, v)
+	case int64:
+		cmp = strings.Compare(text, strconv.FormatInt(v, 10))
+	case float64:
+		cmp = strings.Compare(text, formatReal(v))
+	case []byte:
+		if v == nil {
+			return false
+		}
+		cmp = -1
+	default:
+		return false
+	}
+
+	switch op {
+	case sqlite3.OpEQ:
+		return cmp == 0
+	case sqlite3.OpGT:
+		return cmp > 0
+	case sqlite3.OpGE:
+		return cmp >= 0
+	case sqlite3.OpLT:
+		return cmp < 0
+	case sqlite3.OpLE:
+		return cmp <= 0
+	}
+
+	return false
+}
+
+// formatReal format real value as sqlite converts it to text
+func formatReal(v float64) string {
+	text := strconv.FormatFloat(v, 'g', 15, 64)
+	if strings.ContainsAny(text, ".nN") {
+		return text
+	}
+
+	if mantissa, exponent, ok := strings.Cut(text, "e"); ok {
+		return mantissa + ".0e" + exponent
+	}
+
+	return text + ".0"
+}
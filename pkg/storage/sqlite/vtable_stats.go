@@ -0,0 +1,212 @@
+//go:build sqlite_vtable
+
+package sqlite
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/mattn/go-sqlite3"
+	"io"
+	"os"
+)
+
+const (
+	statsExtension  = ".csvql-stats"
+	statsBlockRows  = 65536
+	statsBufferSize = 64 * 1024
+)
+
+// csvStats min/max values of each column per block of rows, kept next to the csv file
+type csvStats struct {
+	Size      int64      `json:"size"`
+	ModTime   int64      `json:"mod_time"`
+	Delimiter string     `json:"delimiter"`
+	Blocks    []csvBlock `json:"blocks"`
+}
+
+// csvBlock rows starting at a byte offset, Row is the number of rows before the block,
+// Min and Max are nil for columns without values in the block
+type csvBlock struct {
+	Offset int64     `json:"offset"`
+	Row    int64     `json:"row"`
+	Rows   int64     `json:"rows"`
+	Min    []*string `json:"min"`
+	Max    []*string `json:"max"`
+}
+
+// loadStats load stats of the csv file, building them again when missing or outdated
+func loadStats(path string, delimiter rune) (*csvStats, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stat file %s: %w", path, err)
+	}
+
+	if content, err := os.ReadFile(path + statsExtension); err == nil {
+		var stats csvStats
+		if json.Unmarshal(content, &stats) == nil && stats.Size == info.Size() &&
+			stats.ModTime == info.ModTime().UnixNano() && stats.Delimiter == string(delimiter) {
+			return &stats, nil
+		}
+	}
+
+	stats, err := buildStats(path, delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	stats.Size = info.Size()
+	stats.ModTime = info.ModTime().UnixNano()
+
+	// stats are built again on the next use when they can not be kept, read-only directories are still queried
+	if content, err := json.Marshal(stats); err == nil {
+		_ = os.WriteFile(path+statsExtension, content, 0o644)
+	}
+
+	return stats, nil
+}
+
+// buildStats read the csv file computing min/max values per block and the offset where each block starts
+func buildStats(path string, delimiter rune) (*csvStats, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer func(file *os.File) {
+		_ = file.Close()
+	}(file)
+
+	r := csv.NewReader(file)
+	r.Comma = delimiter
+	r.FieldsPerRecord = -1
+
+	if _, err := r.Read(); err != nil {
+		return nil, fmt.Errorf("failed to load headers: %w", err)
+	}
+
+	stats := &csvStats{Delimiter: string(delimiter)}
+	lines := make([]int, 0)
+	var block *csvBlock
+
+	for rows := int64(0); ; rows++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read line: %w", err)
+		}
+
+		if rows%statsBlockRows == 0 {
+			line, _ := r.FieldPos(0)
+			lines = append(lines, line)
+			stats.Blocks = append(stats.Blocks, csvBlock{Row: rows})
+			block = &stats.Blocks[len(stats.Blocks)-1]
+		}
+
+		block.Rows++
+		block.add(record)
+	}
+
+	offsets, err := lineOffsets(file, lines)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range stats.Blocks {
+		stats.Blocks[i].Offset = offsets[i]
+	}
+
+	return stats, nil
+}
+
+// add update min/max values of the block with the record
+func (b *csvBlock) add(record []string) {
+	for len(b.Min) < len(record) {
+		b.Min = append(b.Min, nil)
+		b.Max = append(b.Max, nil)
+	}
+
+	for i := range record {
+		value := record[i]
+		if b.Min[i] == nil || value < *b.Min[i] {
+			b.Min[i] = &value
+		}
+
+		if b.Max[i] == nil || value > *b.Max[i] {
+			b.Max[i] = &value
+		}
+	}
+}
+
+// lineOffsets byte offsets where the given ascending line numbers start, lines start at 1
+func lineOffsets(file *os.File, lines []int) ([]int64, error) {
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("failed to seek file: %w", err)
+	}
+
+	offsets := make([]int64, 0, len(lines))
+	r := bufio.NewReaderSize(file, statsBufferSize)
+	line, offset := 1, int64(0)
+
+	for _, target := range lines {
+		for line < target {
+			chunk, err := r.ReadSlice('\n')
+			offset += int64(len(chunk))
+			if errors.Is(err, bufio.ErrBufferFull) {
+				continue
+			}
+
+			if err != nil {
+				return nil, fmt.Errorf("failed to read file: %w", err)
+			}
+
+			line++
+		}
+
+		offsets = append(offsets, offset)
+	}
+
+	return offsets, nil
+}
+
+// candidates blocks that may have rows matching all filters
+func (s *csvStats) candidates(filters []csvFilter) []csvBlock {
+	blocks := make([]csvBlock, 0, len(s.Blocks))
+	for _, block := range s.Blocks {
+		if block.mayMatch(filters) {
+			blocks = append(blocks, block)
+		}
+	}
+
+	return blocks
+}
+
+// mayMatch check if min/max values of the block allow rows matching all filters
+func (b *csvBlock) mayMatch(filters []csvFilter) bool {
+	for _, f := range filters {
+		if f.column >= len(b.Min) || b.Min[f.column] == nil {
+			return false
+		}
+
+		switch f.op {
+		case sqlite3.OpGT, sqlite3.OpGE:
+			if !compareText(*b.Max[f.column], f.op, f.value) {
+				return false
+			}
+		case sqlite3.OpLT, sqlite3.OpLE:
+			if !compareText(*b.Min[f.column], f.op, f.value) {
+				return false
+			}
+		case sqlite3.OpEQ:
+			if compareText(*b.Min[f.column], sqlite3.OpGT, f.value) || compareText(*b.Max[f.column], sqlite3.OpLT, f.value) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
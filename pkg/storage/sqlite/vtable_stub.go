@@ -0,0 +1,18 @@
+//go:build !sqlite_vtable
+
+package sqlite
+
+import "errors"
+
+// errVirtualTableUnsupported returned when built without the sqlite_vtable tag
+var errVirtualTableUnsupported = errors.New("lazy mode requires csvql built with `-tags sqlite_vtable`")
+
+// CreateVirtualTable not supported without the sqlite_vtable build tag
+func (s *sqLiteStorage) CreateVirtualTable(_ string, _ string, _ rune, _ bool) error {
+	return errVirtualTableUnsupported
+}
+
+// collationGuard nothing to guard without virtual tables
+func collationGuard(_ string) func() {
+	return func() {}
+}
@@ -0,0 +1,82 @@
+//go:build sqlite_vtable
+
+package sqlite_test
+
+import (
+	"adrianolaselva.github.io/csvql/pkg/storage/sqlite"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestShouldQueryVirtualTableWithSuccess(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "orders.csv")
+
+	var content strings.Builder
+	content.WriteString("id;amount\n")
+	for i := 1; i <= 200000; i++ {
+		content.WriteString(fmt.Sprintf("%06d;%d\n", i, i%7))
+	}
+	assert.NoError(t, os.WriteFile(file, []byte(content.String()), 0o644))
+
+	tests := []struct {
+		query   string
+		expects int
+	}{
+		{query: "select count(*) from orders;", expects: 200000},
+		{query: "select count(*) from orders where id = '150000';", expects: 1},
+		{query: "select count(*) from orders where id > '199990';", expects: 10},
+		{query: "select count(*) from orders where id <= 10;", expects: 99999},
+		{query: "select count(*) from orders where id <= '000010' and amount = 3;", expects: 2},
+		{query: "select count(*) from orders where id = 'missing';", expects: 0},
+		{query: "select count(*) from orders where amount is null;", expects: 0},
+		{query: "select count(*) from orders where id = '000001 ' collate rtrim;", expects: 1},
+		{query: "select count(*) from orders where amount = '3 ' Collate RTRIM;", expects: 28572},
+	}
+
+	for _, stats := range []bool{false, true} {
+		storage, err := sqlite.NewSqLiteStorage("")
+		assert.NoError(t, err)
+		assert.NoError(t, storage.CreateVirtualTable("orders", file, ';', stats))
+
+		for _, test := range tests {
+			rows, err := storage.Query(test.query)
+			assert.NoError(t, err)
+
+			assert.True(t, rows.Next())
+			values, err := rows.Values()
+			assert.NoError(t, err)
+			assert.NoError(t, rows.Close())
+			assert.Equal(t, int64(test.expects), values[0], test.query)
+		}
+
+		assert.NoError(t, storage.Close())
+	}
+
+	_, err := os.Stat(file + ".csvql-stats")
+	assert.NoError(t, err)
+}
+
+func TestShouldQueryVirtualTableWithoutWritableStatsWithSuccess(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "orders.csv")
+	assert.NoError(t, os.WriteFile(file, []byte("id;amount\n1;10\n2;20\n3;30\n"), 0o644))
+	assert.NoError(t, os.Mkdir(file+".csvql-stats", 0o755))
+
+	storage, err := sqlite.NewSqLiteStorage("")
+	assert.NoError(t, err)
+	assert.NoError(t, storage.CreateVirtualTable("orders", file, ';', true))
+
+	rows, err := storage.Query("select count(*) from orders where id >= '2';")
+	assert.NoError(t, err)
+	assert.True(t, rows.Next())
+	values, err := rows.Values()
+	assert.NoError(t, err)
+	assert.NoError(t, rows.Close())
+	assert.Equal(t, int64(2), values[0])
+
+	assert.NoError(t, storage.Close())
+}
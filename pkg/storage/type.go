@@ -1,11 +1,89 @@
 package storage
 
-import "database/sql"
+import (
+	"context"
+)
 
 type Storage interface {
 	BuildStructure(string, []string) error
+	MatchRows(columns []string, rows [][]any, where string) ([]bool, error)
 	InsertRow(string, []string, []any) error
-	Query(cmd string) (*sql.Rows, error)
-	ShowTables() (*sql.Rows, error)
+	UpsertRow(string, []string, []string, []any) (bool, error)
+	CreateIndex(string, []string, bool) error
+	Query(cmd string, args ...any) (Rows, error)
+	QueryContext(ctx context.Context, cmd string, args ...any) (Rows, error)
+	ExecContext(ctx context.Context, cmd string, args ...any) (int64, error)
+	ListTables() ([]Table, error)
+	DescribeTable(string) ([]TableColumn, error)
+	ShowSchema(string) ([]string, error)
+	ListIndexes() ([]Index, error)
+	HasTable(string) (bool, error)
+	DropTable(string) error
+	CreateVirtualTable(tableName string, path string, delimiter rune, stats bool) error
+	LoadFingerprint(tableName string, path string) (Fingerprint, bool, error)
+	SaveFingerprint(string, Fingerprint) error
 	Close() error
 }
+
+// FileLoader storage loading files natively, faster than inserting their rows one by one
+type FileLoader interface {
+	LoadFile(tableName string, path string, delimiter rune) ([]string, error)
+}
+
+// RowsInserter storage inserting or upserting many rows at once, faster than storing them one by one
+type RowsInserter interface {
+	InsertRows(tableName string, columns []string, rows [][]any) error
+	UpsertRows(tableName string, columns []string, keys []string, rows [][]any) (int64, error)
+}
+
+const (
+	SqliteEngine = "sqlite"
+	DuckDbEngine = "duckdb"
+)
+
+// Engines storage engines available
+var Engines = []string{SqliteEngine, DuckDbEngine}
+
+// IsEngine check if engine is supported
+func IsEngine(engine string) bool {
+	for _, e := range Engines {
+		if e == engine {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Fingerprint file imported into a table, used to skip importing it again while unchanged
+type Fingerprint struct {
+	Path    string
+	Size    int64
+	ModTime int64
+	Hash    string
+	Options string
+}
+
+// Table table of the schemas catalog, Columns is the list of quoted columns recorded on import like [`id`,`name`]
+type Table struct {
+	ID           int64
+	Name         string
+	Columns      string
+	TotalColumns int64
+}
+
+// TableColumn column of a table, Default is nil when the column has no default value
+type TableColumn struct {
+	Name       string
+	Type       string
+	NotNull    bool
+	Default    *string
+	PrimaryKey bool
+}
+
+// Index index created on the columns of a table
+type Index struct {
+	Name  string
+	Table string
+	SQL   string
+}